@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"io"
+)
+
+const (
+	adtsHeaderSize    = 7 // header without CRC
+	adtsHeaderSizeCRC = 9 // header followed by a CRC
+)
+
+// adtsFrameLength returns the length of the ADTS frame whose header is at
+// the start of hdr, which must hold at least adtsHeaderSize bytes. ok is
+// false unless hdr starts with a sync code and a plausible header.
+func adtsFrameLength(hdr []byte) (n int, ok bool) {
+	if hdr[0] != 0xff || hdr[1]&0xf6 != 0xf0 {
+		return 0, false // no sync code or a layer other than 0
+	}
+	if (hdr[2]>>2)&0x0f >= 13 {
+		return 0, false // reserved sampling frequency index
+	}
+	n = int(hdr[3]&0x03)<<11 | int(hdr[4])<<3 | int(hdr[5]>>5)
+	headerSize := adtsHeaderSize
+	if hdr[1]&0x01 == 0 {
+		headerSize = adtsHeaderSizeCRC
+	}
+	return n, n > headerSize
+}
+
+// ADTSChunker yields chunks of whole AAC frames in ADTS framing, whose
+// headers declare the frame length. Every frame carries the stream format
+// in its header, so each chunk can be decoded in isolation.
+type ADTSChunker struct {
+	chunkStats
+	r          *bufio.Reader
+	targetSize int
+	metrics    Metrics
+	hdr        [adtsHeaderSize]byte
+	frame      []byte // frame read ahead that did not fit into the last chunk
+	err        error
+}
+
+// NewADTSChunker returns a new ADTSChunker that reads from r.
+func NewADTSChunker(r io.Reader, chunkSize int, opts ...Option) *ADTSChunker {
+	o := newOptions(opts)
+	return &ADTSChunker{
+		r:          bufio.NewReader(o.reader(r)),
+		targetSize: chunkSize,
+		metrics:    o.metrics,
+		chunkStats: chunkStats{maxChunks: o.maxChunks},
+		err:        validateChunkSize(chunkSize),
+	}
+}
+
+// Next returns the next chunk or io.EOF when done.
+func (c *ADTSChunker) Next() ([]byte, error) {
+	if c.exhausted() {
+		return nil, io.EOF
+	}
+	chunk, err := c.next()
+	c.count(chunk, err)
+	return observe(c.metrics, chunk, err)
+}
+
+// next groups whole frames into a chunk of at most the target size,
+// unless a single frame is larger.
+func (c *ADTSChunker) next() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	var chunk []byte
+	for frames := 0; ; frames++ {
+		if c.frame == nil {
+			frame, err := c.readFrame()
+			if err != nil {
+				c.err = err
+				if len(chunk) > 0 {
+					return chunk, nil
+				}
+				return nil, err
+			}
+			c.frame = frame
+		}
+		if frames > 0 && len(chunk)+len(c.frame) > c.targetSize {
+			return chunk, nil
+		}
+		chunk = append(chunk, c.frame...)
+		c.frame = nil
+	}
+}
+
+// readFrame reads the next frame, skipping input that does not start one.
+func (c *ADTSChunker) readFrame() ([]byte, error) {
+	n, err := c.findNextFrame()
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, n)
+	copy(frame, c.hdr[:])
+	if _, err := io.ReadFull(c.r, frame[adtsHeaderSize:]); err != nil {
+		return nil, noEOF(err)
+	}
+	return frame, nil
+}
+
+// findNextFrame scans for the next valid frame header, leaving the
+// header in c.hdr, and returns the frame length.
+func (c *ADTSChunker) findNextFrame() (int, error) {
+	for {
+		b, err := c.r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != 0xff {
+			continue
+		}
+
+		// Peek at the rest of the potential header. It stays unread on a
+		// false sync, as the next sync may start within it.
+		next, err := c.r.Peek(adtsHeaderSize - 1)
+		if len(next) < adtsHeaderSize-1 {
+			if err == io.EOF && len(next) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		c.hdr[0] = b
+		copy(c.hdr[1:], next)
+		if n, ok := adtsFrameLength(c.hdr[:]); ok {
+			c.r.Discard(adtsHeaderSize - 1)
+			return n, nil
+		}
+	}
+}
+
+// Close implements Chunker. An ADTSChunker holds no resources, so it is a
+// no-op; closing the input is up to the caller.
+func (c *ADTSChunker) Close() error {
+	return nil
+}
+
+// IndependentChunks reports whether every chunk can be decoded in isolation.
+// ADTS frames carry the stream format in their headers, so it returns true.
+func (c *ADTSChunker) IndependentChunks() bool {
+	return true
+}
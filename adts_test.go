@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// makeADTSFrame returns an AAC-LC stereo 44.1 kHz frame of n bytes.
+func makeADTSFrame(n int, seed byte) []byte {
+	frame := makeAudio(n, seed)
+	copy(frame, []byte{0xff, 0xf1, 0x50, 0x80 | byte(n>>11), byte(n >> 3), byte(n&7)<<5 | 0x1f, 0xfc})
+	return frame
+}
+
+func TestADTSChunker(t *testing.T) {
+	var frames [][]byte
+	for i := 0; i < 12; i++ {
+		frames = append(frames, makeADTSFrame(300+i*20, byte(i)))
+	}
+	stream := bytes.Join(frames, nil)
+
+	// Garbage with a false sync before the first frame is skipped
+	input := append([]byte{0x00, 0xff, 0x12, 0xff, 0xf1}, stream...)
+	chunks := readAllChunks(t, NewADTSChunker(bytes.NewReader(input), 1500))
+	if !bytes.Equal(bytes.Join(chunks, nil), stream) {
+		t.Fatal("chunks do not join to the frames of the input")
+	}
+	next := 0
+	for i, chunk := range chunks {
+		if len(chunk) > 1500 {
+			t.Errorf("chunk %d: %d bytes exceed the chunk size", i, len(chunk))
+		}
+		// Every chunk ends at a frame boundary
+		for n := 0; n < len(chunk); next++ {
+			n += len(frames[next])
+			if n > len(chunk) {
+				t.Fatalf("chunk %d splits frame %d", i, next)
+			}
+		}
+	}
+
+	// A truncated final frame is dropped and reported
+	c := NewADTSChunker(bytes.NewReader(stream[:len(stream)-10]), 1<<20)
+	chunk, err := c.Next()
+	if err != nil || !bytes.Equal(chunk, stream[:len(stream)-len(frames[11])]) {
+		t.Fatalf("Next() = %d bytes, %v; want all but the truncated frame", len(chunk), err)
+	}
+	if _, err := c.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Next() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
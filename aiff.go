@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrInvalidAIFF is returned when the input is not an AIFF or AIFF-C file
+// with a COMM chunk preceding the SSND chunk.
+var ErrInvalidAIFF = errors.New("not a valid AIFF file")
+
+const (
+	aiffCommSize = 18 // COMM payload of AIFF, AIFF-C appends the compression
+	ssndHeadSize = 8  // offset and block size preceding the sound data
+)
+
+// readUint32BE reads a 32-bit big-endian unsigned integer
+func readUint32BE(data []byte) uint32 {
+	return uint32(data[0])<<24 | uint32(data[1])<<16 | uint32(data[2])<<8 | uint32(data[3])
+}
+
+// readUint16BE reads a 16-bit big-endian unsigned integer
+func readUint16BE(data []byte) uint16 {
+	return uint16(data[0])<<8 | uint16(data[1])
+}
+
+// AIFFChunker yields AIFF chunks as complete AIFF files, like a WAVChunker
+// in complete mode. Every chunk carries the chunks of the input preceding
+// the sound data, with the sample frame count of the COMM chunk and the
+// sizes patched. The sound data of the input may be preceded by alignment
+// bytes given by the SSND offset; they are dropped, so the offset and the
+// block size are zero in every chunk.
+type AIFFChunker struct {
+	chunkStats
+	r          io.Reader
+	targetSize int
+	metrics    Metrics
+	header     []byte // FORM header, chunks before SSND and SSND header
+	commOffset int    // offset of the COMM payload within header
+	frameSize  int    // bytes per sample frame
+	left       int64  // sound data bytes not read yet
+	started    bool   // the header was read
+	err        error
+}
+
+// NewAIFFChunker returns a new AIFFChunker that reads from r.
+func NewAIFFChunker(r io.Reader, chunkSize int, opts ...Option) *AIFFChunker {
+	o := newOptions(opts)
+	return &AIFFChunker{
+		r:          o.reader(r),
+		targetSize: chunkSize,
+		metrics:    o.metrics,
+		chunkStats: chunkStats{maxChunks: o.maxChunks},
+		err:        validateChunkSize(chunkSize),
+	}
+}
+
+// Next returns the next chunk or io.EOF when done.
+func (c *AIFFChunker) Next() ([]byte, error) {
+	if c.exhausted() {
+		return nil, io.EOF
+	}
+	chunk, err := c.next()
+	c.count(chunk, err)
+	return observe(c.metrics, chunk, err)
+}
+
+// next reads the sound data of the next chunk and wraps it with the header.
+func (c *AIFFChunker) next() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if !c.started {
+		c.started = true
+		if err := c.readHeader(); err != nil {
+			c.err = err
+			return nil, err
+		}
+	}
+
+	size := int64(c.readSize())
+	if size > c.left {
+		size = c.left
+	}
+	audio := make([]byte, size)
+	n, err := io.ReadFull(c.r, audio)
+	c.left -= int64(n)
+	if isErrNotEOF(err) {
+		c.err = err
+		return nil, err
+	}
+	if err != nil {
+		// A truncated file ends with the whole sample frames read
+		c.left = 0
+		n -= n % c.frameSize
+	}
+	if n == 0 {
+		c.err = io.EOF
+		return nil, io.EOF
+	}
+	return c.createAIFFFile(audio[:n]), nil
+}
+
+// readSize returns the number of sound data bytes of a chunk, leaving
+// room for the header and rounded down to whole sample frames.
+func (c *AIFFChunker) readSize() int {
+	n := c.targetSize - len(c.header)
+	if n <= 0 {
+		n = minChunkSize
+	}
+	n -= n % c.frameSize
+	return max(n, c.frameSize)
+}
+
+// readHeader reads the chunks up to the start of the sound data.
+func (c *AIFFChunker) readHeader() error {
+	form := make([]byte, 12)
+	if _, err := io.ReadFull(c.r, form); err != nil {
+		return truncated(err, "FORM header")
+	}
+	if !compareID(form[0:4], "FORM") || !compareID(form[8:12], "AIFF") && !compareID(form[8:12], "AIFC") {
+		return ErrInvalidAIFF
+	}
+	c.header = form
+
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(c.r, chunk); err != nil {
+			return truncated(err, "chunk header")
+		}
+		size := int64(readUint32BE(chunk[4:8]))
+
+		if compareID(chunk[0:4], "SSND") {
+			if c.commOffset == 0 || size < ssndHeadSize {
+				return ErrInvalidAIFF
+			}
+			head := make([]byte, ssndHeadSize)
+			if _, err := io.ReadFull(c.r, head); err != nil {
+				return truncated(err, "SSND chunk")
+			}
+			// Skip the alignment bytes preceding the sound data
+			offset := int64(readUint32BE(head[0:4]))
+			if _, err := io.CopyN(io.Discard, c.r, offset); err != nil {
+				return truncated(err, "SSND chunk")
+			}
+			c.left = max(size-ssndHeadSize-offset, 0)
+			c.header = append(c.header, chunk...)
+			c.header = append(c.header, make([]byte, ssndHeadSize)...)
+			return nil
+		}
+
+		if size > maxMetadataChunkSize {
+			return ErrChunkTooLarge
+		}
+		if len(c.header)+8+int(size) > maxHeaderSize {
+			return errors.New("aiff header too large")
+		}
+		c.header = append(c.header, chunk...)
+		start := len(c.header)
+		c.header = append(c.header, make([]byte, size+size%2)...)
+		if _, err := io.ReadFull(c.r, c.header[start:]); err != nil {
+			return truncated(err, "chunk data")
+		}
+
+		if compareID(chunk[0:4], "COMM") {
+			if size < aiffCommSize {
+				return ErrInvalidAIFF
+			}
+			comm := c.header[start:]
+			channels := int(readUint16BE(comm[0:2]))
+			bits := int(readUint16BE(comm[6:8]))
+			c.commOffset = start
+			c.frameSize = max(channels*((bits+7)/8), 1)
+		}
+	}
+}
+
+// createAIFFFile wraps the sound data with the header, patching the
+// sample frame count and the sizes. Odd-sized sound data is followed by
+// the pad byte IFF requires.
+func (c *AIFFChunker) createAIFFFile(audio []byte) []byte {
+	total := len(c.header) + len(audio) + len(audio)%2
+	file := make([]byte, total)
+	copy(file, c.header)
+	copy(file[len(c.header):], audio)
+
+	binary.BigEndian.PutUint32(file[4:8], uint32(total-8))
+	binary.BigEndian.PutUint32(file[c.commOffset+2:c.commOffset+6], uint32(len(audio)/c.frameSize))
+	ssnd := len(c.header) - ssndHeadSize - 4 // SSND size field
+	binary.BigEndian.PutUint32(file[ssnd:ssnd+4], uint32(ssndHeadSize+len(audio)))
+	return file
+}
+
+// Close implements Chunker. An AIFFChunker holds no resources, so it is a
+// no-op; closing the input is up to the caller.
+func (c *AIFFChunker) Close() error {
+	return nil
+}
+
+// IndependentChunks reports whether every chunk can be decoded in isolation.
+// AIFF chunks are always complete AIFF files.
+func (c *AIFFChunker) IndependentChunks() bool {
+	return true
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// makeAIFF returns an AIFF file of 16-bit audio whose SSND chunk starts
+// with offset alignment bytes.
+func makeAIFF(channels int, data []byte, offset int) []byte {
+	comm := make([]byte, aiffCommSize)
+	binary.BigEndian.PutUint16(comm[0:2], uint16(channels))
+	binary.BigEndian.PutUint32(comm[2:6], uint32(len(data)/(2*channels)))
+	binary.BigEndian.PutUint16(comm[6:8], 16)
+	copy(comm[8:18], []byte{0x40, 0x0e, 0xac, 0x44}) // 44100 Hz as 80-bit float
+
+	var b bytes.Buffer
+	b.WriteString("FORM\x00\x00\x00\x00AIFF")
+	b.WriteString("COMM")
+	binary.Write(&b, binary.BigEndian, uint32(len(comm)))
+	b.Write(comm)
+	b.WriteString("SSND")
+	binary.Write(&b, binary.BigEndian, uint32(ssndHeadSize+offset+len(data)))
+	binary.Write(&b, binary.BigEndian, uint32(offset))
+	binary.Write(&b, binary.BigEndian, uint32(0))
+	b.Write(make([]byte, offset))
+	b.Write(data)
+	file := b.Bytes()
+	binary.BigEndian.PutUint32(file[4:8], uint32(len(file)-8))
+	return file
+}
+
+func TestAIFFChunker(t *testing.T) {
+	data := makeAudio(10000, 0x27)
+	input := makeAIFF(2, data, 4)
+	const headerLen = 12 + 8 + aiffCommSize + 8 + ssndHeadSize
+
+	chunks := readAllChunks(t, NewAIFFChunker(bytes.NewReader(input), 4096))
+	if len(chunks) != 3 {
+		t.Fatalf("got %d chunks, want 3", len(chunks))
+	}
+	var audio []byte
+	for i, chunk := range chunks {
+		if size := int(readUint32BE(chunk[4:8])); size != len(chunk)-8 {
+			t.Errorf("chunk %d: FORM size %d, want %d", i, size, len(chunk)-8)
+		}
+		n := len(chunk) - headerLen
+		if i < len(chunks)-1 && n != 4096-headerLen-(4096-headerLen)%4 {
+			t.Errorf("chunk %d: %d bytes of audio are not the whole frames that fit", i, n)
+		}
+		if frames := int(readUint32BE(chunk[22:26])); frames != n/4 {
+			t.Errorf("chunk %d: %d sample frames, want %d", i, frames, n/4)
+		}
+		ssnd := chunk[headerLen-16:]
+		if !compareID(ssnd, "SSND") || int(readUint32BE(ssnd[4:8])) != ssndHeadSize+n {
+			t.Errorf("chunk %d: bad SSND chunk header", i)
+		}
+		if readUint32BE(ssnd[8:12]) != 0 || readUint32BE(ssnd[12:16]) != 0 {
+			t.Errorf("chunk %d: SSND offset and block size not zeroed", i)
+		}
+		if !bytes.Equal(chunk, makeAIFF(2, chunk[headerLen:], 0)) {
+			t.Errorf("chunk %d is not a complete AIFF file", i)
+		}
+		audio = append(audio, chunk[headerLen:]...)
+	}
+	if !bytes.Equal(audio, data) {
+		t.Error("audio of the chunks differs from the input")
+	}
+
+	if _, err := NewAIFFChunker(bytes.NewReader(makeWAV(1, 8000, 16, data)), 4096).Next(); err != ErrInvalidAIFF {
+		t.Errorf("Next() error = %v, want ErrInvalidAIFF", err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// BextInfo holds the EBU broadcast audio extension chunk of a Broadcast
+// Wave (BWF) file. TimeReference is the position of the first sample
+// counted in samples since midnight.
+type BextInfo struct {
+	Description         string
+	Originator          string
+	OriginatorReference string
+	OriginationDate     string // yyyy-mm-dd
+	OriginationTime     string // hh:mm:ss
+	TimeReference       uint64
+	Version             uint16
+	CodingHistory       string
+}
+
+const (
+	bextFixedSize     = 602 // size of the bext chunk without the coding history
+	bextTimeRefOffset = 338
+)
+
+// parseBextChunk decodes a bext chunk. Fields missing from a short chunk
+// are left empty.
+func parseBextChunk(data []byte) *BextInfo {
+	fixed := make([]byte, bextFixedSize)
+	copy(fixed, data)
+
+	off := 0
+	text := func(n int) string {
+		s := string(bytes.TrimRight(fixed[off:off+n], "\x00"))
+		off += n
+		return s
+	}
+
+	info := &BextInfo{
+		Description:         text(256),
+		Originator:          text(32),
+		OriginatorReference: text(32),
+		OriginationDate:     text(10),
+		OriginationTime:     text(8),
+	}
+	info.TimeReference = binary.LittleEndian.Uint64(fixed[off : off+8])
+	info.Version = readUint16LE(fixed[off+8 : off+10])
+	if len(data) > bextFixedSize {
+		info.CodingHistory = string(bytes.TrimRight(data[bextFixedSize:], "\x00"))
+	}
+	return info
+}
+
+// Bext returns the parsed bext chunk and whether it was present. It is
+// available after the first call to Next or to ReadHeader.
+func (c *WAVChunker) Bext() (BextInfo, bool) {
+	if c.bext == nil {
+		return BextInfo{}, false
+	}
+	return *c.bext, true
+}
+
+// addBext inserts an empty bext chunk before the data chunk header unless
+// the header already holds one large enough to carry a time reference.
+func (c *WAVChunker) addBext() {
+	if c.bextOffset > 0 {
+		return
+	}
+	dataHdr := append([]byte(nil), c.header[len(c.header)-8:]...)
+	c.header = append(c.header[:len(c.header)-8], "bext"...)
+	c.header = append(c.header, writeUint32LE(bextFixedSize)...)
+	c.bextOffset = len(c.header)
+	c.header = append(c.header, make([]byte, bextFixedSize)...)
+	c.header = append(c.header, dataHdr...)
+	c.dataSizeOffset = int64(len(c.header) - 4)
+}
+
+// stampTimeReference sets the time reference of the bext chunk of a
+// complete-mode chunk to the position of its first sample: the time
+// reference of the source plus the samples of the preceding chunks.
+func (c *WAVChunker) stampTimeReference(chunk []byte) {
+	var ref uint64
+	if c.bext != nil {
+		ref = c.bext.TimeReference
+	}
+	off := c.bextOffset + bextTimeRefOffset
+	binary.LittleEndian.PutUint64(chunk[off:off+8], ref+c.samplePos)
+}
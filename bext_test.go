@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestWAVChunkerBextTimeReference(t *testing.T) {
+	const frameSize = 4 // 16-bit stereo
+	data := makeAudio(frameSize*5000, 0x3c)
+
+	source := make([]byte, bextFixedSize)
+	copy(source, "Morning news")
+	binary.LittleEndian.PutUint64(source[bextTimeRefOffset:], 48000*3600)
+
+	tests := []struct {
+		name string
+		wav  []byte
+		seed uint64
+	}{
+		{"no bext", makeWAV(2, 48000, 16, data), 0},
+		{"source bext", insertChunk(makeWAV(2, 48000, 16, data), "bext", source), 48000 * 3600},
+	}
+	for _, tt := range tests {
+		chunker := NewWAVChunker(bytes.NewReader(tt.wav), WithBextTimeReference())
+		chunker.targetSize = 4096
+
+		var pos uint64
+		var audio []byte
+		for i, chunk := range readAllChunks(t, chunker) {
+			sub := NewWAVChunker(bytes.NewReader(chunk), WithWAVMode(WAVModeHeaderless))
+			payload := readAllChunks(t, sub)
+			bext, ok := sub.Bext()
+			if !ok {
+				t.Fatalf("%s: chunk %d has no bext chunk", tt.name, i)
+			}
+			if bext.TimeReference != tt.seed+pos {
+				t.Errorf("%s: chunk %d: TimeReference = %d, want %d", tt.name, i, bext.TimeReference, tt.seed+pos)
+			}
+			pos += uint64(len(payload[0]) / frameSize)
+			audio = append(audio, payload[0]...)
+		}
+		if !bytes.Equal(audio, data) {
+			t.Errorf("%s: concatenated audio differs from the data region", tt.name)
+		}
+	}
+
+	// The source bext chunk is parsed without the option too
+	chunker := NewWAVChunker(bytes.NewReader(tests[1].wav))
+	if err := chunker.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+	if bext, ok := chunker.Bext(); !ok || bext.Description != "Morning news" || bext.TimeReference != 48000*3600 {
+		t.Errorf("Bext() = %+v, %v", bext, ok)
+	}
+}
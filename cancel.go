@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrCanceled is returned by Next once the chunker has been canceled.
+var ErrCanceled = errors.New("chunker canceled")
+
+// canceler lets a chunker be canceled from another goroutine. Next runs
+// with mu held, possibly blocked on reading the input, so Cancel only sets
+// the canceled flag then and the cleanup runs once Next returns.
+type canceler struct {
+	mu       sync.Mutex
+	canceled atomic.Bool
+}
+
+// guard runs next with mu held, failing with ErrCanceled and running
+// cleanup when the chunker was canceled before or while next ran.
+func (c *canceler) guard(next func() ([]byte, error), cleanup func()) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.canceled.Load() {
+		cleanup()
+		return nil, ErrCanceled
+	}
+	chunk, err := next()
+	if c.canceled.Load() {
+		cleanup()
+		return nil, ErrCanceled
+	}
+	return chunk, err
+}
+
+// cancel marks the chunker as canceled without waiting for an in-flight
+// Next. cleanup runs at once if mu is free, otherwise as soon as the
+// holder of mu returns, unless the chunker was reset meanwhile.
+func (c *canceler) cancel(cleanup func()) {
+	c.canceled.Store(true)
+	if c.mu.TryLock() {
+		defer c.mu.Unlock()
+		cleanup()
+		return
+	}
+	go func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.canceled.Load() {
+			cleanup()
+		}
+	}()
+}
+
+// Cancel aborts the chunking: a Next running concurrently, or any later
+// one, returns ErrCanceled, and the pooled buffers are released. It does
+// not wait for an in-flight Next, whose buffers are released once it
+// returns, and is safe to call from any goroutine.
+func (c *WAVChunker) Cancel() {
+	c.cancel(c.cancelCleanup)
+}
+
+// cancelCleanup releases the buffers of a canceled WAVChunker.
+func (c *WAVChunker) cancelCleanup() {
+	c.peeked = false
+	c.reset()
+	c.err = ErrCanceled
+}
+
+// Cancel aborts the chunking: a Next running concurrently, or any later
+// one, returns ErrCanceled. It does not wait for an in-flight Next and is
+// safe to call from any goroutine.
+func (c *MP3Chunker) Cancel() {
+	c.cancel(c.cancelCleanup)
+}
+
+func (c *MP3Chunker) cancelCleanup() {
+	c.reservoir = nil
+	c.err = ErrCanceled
+}
+
+// Cancel aborts the chunking: a Next running concurrently, or any later
+// one, returns ErrCanceled. It does not wait for an in-flight Next and is
+// safe to call from any goroutine.
+func (c *DumbChunker) Cancel() {
+	c.cancel(c.cancelCleanup)
+}
+
+func (c *DumbChunker) cancelCleanup() {
+	c.err = ErrCanceled
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestCancel(t *testing.T) {
+	wav := makeWAV(2, 44100, 16, makeAudio(1<<20, 0x5a))
+	mp3 := makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, 2000)
+
+	type cancelChunker interface {
+		Chunker
+		Cancel()
+	}
+	tests := []struct {
+		name string
+		c    cancelChunker
+	}{
+		{"wav", NewWAVChunker(&slowReader{r: bytes.NewReader(wav)})},
+		{"mp3", NewMP3Chunker(&slowReader{r: bytes.NewReader(mp3)}, 4096, 511)},
+		{"dumb", NewDumbChunker(&slowReader{r: bytes.NewReader(wav)}, 4096)},
+	}
+
+	for _, tt := range tests {
+		done := make(chan error)
+		go func() {
+			for {
+				if _, err := tt.c.Next(); err != nil {
+					done <- err
+					return
+				}
+			}
+		}()
+
+		time.Sleep(time.Millisecond)
+		tt.c.Cancel()
+		if err := <-done; err != ErrCanceled && err != io.EOF {
+			t.Errorf("%s: Next() error %v, want %v", tt.name, err, ErrCanceled)
+		}
+		if _, err := tt.c.Next(); err != ErrCanceled {
+			t.Errorf("%s: Next() after Cancel error %v, want %v", tt.name, err, ErrCanceled)
+		}
+	}
+}
+
+// blockingReader blocks reads until unblock is closed, telling when the
+// first read started.
+type blockingReader struct {
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.started:
+	default:
+		close(r.started)
+	}
+	<-r.unblock
+	return 0, io.EOF
+}
+
+func TestCancelDuringBlockedRead(t *testing.T) {
+	r := &blockingReader{started: make(chan struct{}), unblock: make(chan struct{})}
+	c := NewWAVChunker(r)
+
+	done := make(chan error)
+	go func() {
+		_, err := c.Next()
+		done <- err
+	}()
+	<-r.started
+
+	canceled := make(chan struct{})
+	go func() {
+		c.Cancel()
+		close(canceled)
+	}()
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("Cancel() waits for the blocked Next")
+	}
+
+	close(r.unblock)
+	if err := <-done; err != ErrCanceled {
+		t.Errorf("Next() error %v, want %v", err, ErrCanceled)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.audio != nil {
+		t.Error("buffers kept after the canceled Next returned")
+	}
+}
@@ -0,0 +1,18 @@
+package main
+
+// Chain wraps c with wrappers in order and returns the outermost chunker.
+//
+// The first wrapper is applied directly to c, so it is the first one to
+// process every chunk, and the last wrapper produces the chunks returned by
+// the resulting chunker. For example, to compress chunks before encrypting
+// them:
+//
+//	Chain(c, compress, encrypt)
+//
+// Consumers have to undo the wrappers in reverse order: decrypt, then decompress.
+func Chain(c Chunker, wrappers ...func(Chunker) Chunker) Chunker {
+	for _, wrap := range wrappers {
+		c = wrap(c)
+	}
+	return c
+}
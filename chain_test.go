@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+)
+
+// funcChunker transforms every chunk of the wrapped chunker with fn.
+type funcChunker struct {
+	c  Chunker
+	fn func([]byte) ([]byte, error)
+}
+
+func (f *funcChunker) Next() ([]byte, error) {
+	chunk, err := f.c.Next()
+	if err != nil {
+		return nil, err
+	}
+	return f.fn(chunk)
+}
+
+func (f *funcChunker) Close() error {
+	return f.c.Close()
+}
+
+func gzipWrapper(c Chunker) Chunker {
+	return &funcChunker{c: c, fn: func(chunk []byte) ([]byte, error) {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(chunk); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}}
+}
+
+func checksumWrapper(c Chunker) Chunker {
+	return &funcChunker{c: c, fn: func(chunk []byte) ([]byte, error) {
+		return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk)), nil
+	}}
+}
+
+func TestChain(t *testing.T) {
+	data := makeAudio(10000, 0x3c)
+	chunker := Chain(NewDumbChunker(bytes.NewReader(data), 1024), gzipWrapper, checksumWrapper)
+
+	var got []byte
+	for i, chunk := range readAllChunks(t, chunker) {
+		// Undo the wrappers in reverse order
+		payload, sum := chunk[:len(chunk)-4], binary.BigEndian.Uint32(chunk[len(chunk)-4:])
+		if crc32.ChecksumIEEE(payload) != sum {
+			t.Fatalf("chunk %d: checksum mismatch", i)
+		}
+		r, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		p, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		got = append(got, p...)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %d bytes, want %d bytes", len(got), len(data))
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// ErrChecksumMismatch is returned by JSONChunkDecoder when a chunk does
+// not match the digest recorded for it.
+var ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+
+// Checksum algorithms of the JSON output, selected with -checksum.
+const (
+	checksumSHA256 = "sha256"
+	checksumCRC32  = "crc32"
+)
+
+// validChecksum reports whether algo names a supported checksum algorithm,
+// or none when empty.
+func validChecksum(algo string) bool {
+	switch algo {
+	case "", checksumSHA256, checksumCRC32:
+		return true
+	}
+	return false
+}
+
+// addChecksum records the hex digest of chunk in the field of v for algo.
+func addChecksum(v *DataChunk, algo string, chunk []byte) {
+	switch algo {
+	case checksumSHA256:
+		sum := sha256.Sum256(chunk)
+		v.SHA256 = hex.EncodeToString(sum[:])
+	case checksumCRC32:
+		v.CRC32 = hex.EncodeToString(binary.BigEndian.AppendUint32(nil, crc32.ChecksumIEEE(chunk)))
+	}
+}
+
+// verifyChecksum checks chunk against the digests recorded in v, if any.
+func verifyChecksum(v DataChunk, chunk []byte) error {
+	want := v
+	want.SHA256, want.CRC32 = "", ""
+	if v.SHA256 != "" {
+		addChecksum(&want, checksumSHA256, chunk)
+	}
+	if v.CRC32 != "" {
+		addChecksum(&want, checksumCRC32, chunk)
+	}
+	if want.SHA256 != v.SHA256 {
+		return fmt.Errorf("%w: sha256 %s, want %s", ErrChecksumMismatch, want.SHA256, v.SHA256)
+	}
+	if want.CRC32 != v.CRC32 {
+		return fmt.Errorf("%w: crc32 %s, want %s", ErrChecksumMismatch, want.CRC32, v.CRC32)
+	}
+	return nil
+}
@@ -1,44 +1,153 @@
 package main
 
 import (
+	"errors"
 	"io"
+	"strings"
 )
 
-// Chunker interface for different audio file types
+// ErrShortFinalChunk is returned by a DumbChunker with PolicyError when the
+// input size is not a multiple of the chunk size.
+var ErrShortFinalChunk = errors.New("final chunk is shorter than the chunk size")
+
+// FinalChunkPolicy controls what DumbChunker does with a final chunk that is
+// shorter than the chunk size.
+type FinalChunkPolicy int
+
+const (
+	// PolicyShort returns the short final chunk as is.
+	PolicyShort FinalChunkPolicy = iota
+	// PolicyDrop discards the short final chunk.
+	PolicyDrop
+	// PolicyError fails with ErrShortFinalChunk instead of returning the short final chunk.
+	PolicyError
+)
+
+// Chunker interface for different audio file types. Close releases the
+// resources of the chunker; it is safe to call more than once.
 type Chunker interface {
 	Next() ([]byte, error)
+	Close() error
+}
+
+// ChunksAreIndependent reports whether every chunk produced for fileType
+// with the default settings can be decoded in isolation.
+func ChunksAreIndependent(fileType string) bool {
+	switch strings.ToLower(fileType) {
+	case "wav", "dumb", "aac", "aiff":
+		return true
+	case "mp3":
+		// Chunks carry the bit reservoir of the previous chunk
+		return true
+	}
+	return false
 }
 
 // DumbChunker splits any file into fixed-size chunks without parsing
 type DumbChunker struct {
+	canceler
+	chunkStats
 	r          io.Reader
 	targetSize int
+	policy     FinalChunkPolicy
+	metrics    Metrics
+	reuse      bool
+	buf        []byte // chunk buffer shared by all chunks, see WithReuseBuffer
+	lastLen    int    // length of the last returned chunk
 	err        error
 }
 
 // NewDumbChunker returns a new DumbChunker that reads from r.
-func NewDumbChunker(r io.Reader, chunkSize int) *DumbChunker {
+func NewDumbChunker(r io.Reader, chunkSize int, opts ...Option) *DumbChunker {
+	o := newOptions(opts)
 	return &DumbChunker{
-		r:          r,
+		r:          o.reader(r),
 		targetSize: chunkSize,
+		policy:     o.finalChunkPolicy,
+		metrics:    o.metrics,
+		chunkStats: chunkStats{maxChunks: o.maxChunks},
+		reuse:      o.reuseBuffer,
+		err:        validateChunkSize(chunkSize),
 	}
 }
 
-// Next returns the next chunk or io.EOF when done.
+// Next returns the next chunk or io.EOF when done. With WithReuseBuffer
+// the chunk is only valid until the following call.
 func (c *DumbChunker) Next() ([]byte, error) {
+	if c.exhausted() {
+		return nil, io.EOF
+	}
+	chunk, err := c.guard(c.next, c.cancelCleanup)
+	if err == nil {
+		c.lastLen = len(chunk)
+	}
+	c.count(chunk, err)
+	return observe(c.metrics, chunk, err)
+}
+
+// next reads the next chunk.
+func (c *DumbChunker) next() ([]byte, error) {
 	if c.err != nil {
 		return nil, c.err
 	}
 
-	chunk := make([]byte, c.targetSize)
-	n, err := c.r.Read(chunk)
-	if err != nil {
-		if err == io.EOF && n == 0 {
-			return nil, io.EOF
-		}
+	if c.policy != PolicyShort {
+		return c.nextFull()
+	}
+
+	// Fill the whole chunk, so readers returning less than asked for,
+	// like pipes and network connections, do not cause short chunks
+	chunk := c.buffer()
+	n, err := io.ReadFull(c.r, chunk)
+	switch {
+	case err == io.ErrUnexpectedEOF:
+		c.err = io.EOF
+		return chunk[:n], nil
+	case err != nil:
 		c.err = err
 		return nil, err
 	}
+	return chunk, nil
+}
+
+// nextFull reads a full chunk, applying the final chunk policy
+// when the input ends before the chunk is filled.
+func (c *DumbChunker) nextFull() ([]byte, error) {
+	chunk := c.buffer()
+	_, err := io.ReadFull(c.r, chunk)
+	switch {
+	case err == io.ErrUnexpectedEOF && c.policy == PolicyError:
+		c.err = ErrShortFinalChunk
+		return nil, c.err
+	case err == io.ErrUnexpectedEOF || err == io.EOF:
+		c.err = io.EOF
+		return nil, io.EOF
+	case err != nil:
+		c.err = err
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// buffer returns the buffer to read the next chunk into.
+func (c *DumbChunker) buffer() []byte {
+	if !c.reuse {
+		return make([]byte, c.targetSize)
+	}
+	if c.buf == nil {
+		c.buf = make([]byte, c.targetSize)
+	}
+	return c.buf
+}
+
+// Close implements Chunker. A DumbChunker holds no resources, so it is a
+// no-op; closing the input is up to the caller.
+func (c *DumbChunker) Close() error {
+	return nil
+}
 
-	return chunk[:n], nil
+// IndependentChunks reports whether every chunk can be decoded in isolation.
+// Dumb chunks carry no format dependencies, so it always returns true.
+func (c *DumbChunker) IndependentChunks() bool {
+	return true
 }
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"testing/iotest"
+)
+
+func TestChunksAreIndependent(t *testing.T) {
+	tests := map[string]bool{
+		"wav":  true,
+		"WAV":  true,
+		"dumb": true,
+		"mp3":  true,
+		"ogg":  false,
+	}
+	for fileType, want := range tests {
+		if got := ChunksAreIndependent(fileType); got != want {
+			t.Errorf("ChunksAreIndependent(%q) = %v, want %v", fileType, got, want)
+		}
+	}
+}
+
+func TestIndependentChunks(t *testing.T) {
+	r := bytes.NewReader(nil)
+	tests := []struct {
+		name    string
+		chunker interface{ IndependentChunks() bool }
+		want    bool
+	}{
+		{"dumb", NewDumbChunker(r, 1024), true},
+		{"wav complete", NewWAVChunker(r), true},
+		{"wav headerless", NewWAVChunker(r, WithWAVMode(WAVModeHeaderless)), false},
+		{"mp3 with reservoir", NewMP3Chunker(r, 8192, 511), true},
+		{"mp3 without reservoir", NewMP3Chunker(r, 8192, 0), false},
+	}
+	for _, tt := range tests {
+		if got := tt.chunker.IndependentChunks(); got != tt.want {
+			t.Errorf("%s: IndependentChunks() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestChunkerClose(t *testing.T) {
+	for _, fileType := range SupportedTypes() {
+		c, err := NewChunker(fileType, bytes.NewReader(makeAudio(4096, 0x02)), 1024)
+		if err != nil {
+			t.Fatalf("%s: NewChunker() error: %v", fileType, err)
+		}
+		// Closing is safe both before and after chunking ends
+		if err := c.Close(); err != nil {
+			t.Errorf("%s: Close() error: %v", fileType, err)
+		}
+		if err := c.Close(); err != nil {
+			t.Errorf("%s: second Close() error: %v", fileType, err)
+		}
+	}
+}
+
+func TestDumbChunkerFinalChunkPolicy(t *testing.T) {
+	data := makeAudio(2560, 0x01)
+
+	tests := []struct {
+		policy  FinalChunkPolicy
+		lengths []int
+		err     error
+	}{
+		{PolicyShort, []int{1024, 1024, 512}, nil},
+		{PolicyDrop, []int{1024, 1024}, nil},
+		{PolicyError, []int{1024, 1024}, ErrShortFinalChunk},
+	}
+
+	for _, tt := range tests {
+		chunker := NewDumbChunker(bytes.NewReader(data), 1024, WithFinalChunkPolicy(tt.policy))
+
+		var lengths []int
+		var err error
+		for {
+			var chunk []byte
+			if chunk, err = chunker.Next(); err != nil {
+				break
+			}
+			lengths = append(lengths, len(chunk))
+		}
+
+		if tt.err == nil && err != io.EOF {
+			t.Errorf("policy %d: got error %v, want io.EOF", tt.policy, err)
+		}
+		if tt.err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("policy %d: got error %v, want %v", tt.policy, err, tt.err)
+		}
+		if !reflect.DeepEqual(lengths, tt.lengths) {
+			t.Errorf("policy %d: got chunk lengths %v, want %v", tt.policy, lengths, tt.lengths)
+		}
+	}
+}
+
+func TestDumbChunkerShortReads(t *testing.T) {
+	data := makeAudio(2560, 0x01)
+	chunker := NewDumbChunker(iotest.OneByteReader(bytes.NewReader(data)), 1024)
+
+	var lengths []int
+	var got []byte
+	for _, chunk := range readAllChunks(t, chunker) {
+		lengths = append(lengths, len(chunk))
+		got = append(got, chunk...)
+	}
+	if want := []int{1024, 1024, 512}; !reflect.DeepEqual(lengths, want) {
+		t.Errorf("got chunk lengths %v, want %v", lengths, want)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("chunks do not add up to the input")
+	}
+}
+
+func TestDumbChunkerReuseBuffer(t *testing.T) {
+	data := makeAudio(2560, 0x01)
+	chunker := NewDumbChunker(bytes.NewReader(data), 1024, WithReuseBuffer())
+
+	var got []byte
+	var first []byte
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		if first == nil {
+			first = chunk
+		} else if &chunk[0] != &first[0] {
+			t.Error("chunk does not reuse the buffer of the first chunk")
+		}
+		got = append(got, chunk...)
+	}
+	if !bytes.Equal(got, data) {
+		t.Error("chunks do not add up to the input")
+	}
+}
+
+func BenchmarkDumbChunker(b *testing.B) {
+	data := makeAudio(1<<20, 0x01)
+	for name, opts := range map[string][]Option{
+		"alloc": nil,
+		"reuse": {WithReuseBuffer()},
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				c := NewDumbChunker(bytes.NewReader(data), 4096, opts...)
+				for {
+					if _, err := c.Next(); err == io.EOF {
+						break
+					} else if err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import "io"
+
+// chunkReader reads the chunks of a Chunker back to back.
+type chunkReader struct {
+	c   Chunker
+	buf []byte // unread rest of the current chunk
+	err error
+}
+
+// NewChunkReader returns a reader of the chunks of c joined together, so
+// the output of a chunker can be passed to anything taking an io.Reader.
+// Chunks that do not fit into the buffer passed to Read are kept for the
+// following calls. The reader returns io.EOF once c does and any other
+// error of c once the chunks read before it are consumed.
+func NewChunkReader(c Chunker) io.Reader {
+	return &chunkReader{c: c}
+}
+
+// Read implements io.Reader.
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		// Empty chunks are skipped
+		r.buf, r.err = r.c.Next()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+	"testing/iotest"
+)
+
+// sliceChunker returns the given chunks, then err.
+type sliceChunker struct {
+	chunks [][]byte
+	err    error
+}
+
+func (c *sliceChunker) Next() ([]byte, error) {
+	if len(c.chunks) == 0 {
+		return nil, c.err
+	}
+	chunk := c.chunks[0]
+	c.chunks = c.chunks[1:]
+	return chunk, nil
+}
+
+func (c *sliceChunker) Close() error {
+	return nil
+}
+
+func TestChunkReader(t *testing.T) {
+	data, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := bytes.Join(readAllChunks(t, NewWAVChunker(bytes.NewReader(data))), nil)
+
+	got, err := io.ReadAll(NewChunkReader(NewWAVChunker(bytes.NewReader(data))))
+	if err != nil {
+		t.Fatalf("ReadAll() error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %d bytes, want the %d bytes of the joined chunks", len(got), len(want))
+	}
+
+	// Reads much smaller than the chunks
+	r := iotest.OneByteReader(NewChunkReader(NewWAVChunker(bytes.NewReader(data))))
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, want) {
+		t.Fatalf("one byte reads: got %d bytes, %v", len(got), err)
+	}
+
+	// Empty chunks are skipped and errors come after the data
+	errBroken := errors.New("broken")
+	c := &sliceChunker{chunks: [][]byte{[]byte("ab"), {}, nil, []byte("cde")}, err: errBroken}
+	got, err = io.ReadAll(NewChunkReader(c))
+	if string(got) != "abcde" || err != errBroken {
+		t.Fatalf("got %q, %v, want %q, %v", got, err, "abcde", errBroken)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// IndexEntry locates a chunk within the data written by WriteConcatWithIndex.
+type IndexEntry struct {
+	Offset int64 `json:"offset"`
+	Length int   `json:"length"`
+}
+
+// WriteConcatWithIndex writes the chunks of c back to back to data and,
+// for every chunk, an IndexEntry as a line of JSON to index, so that any
+// chunk can be read back with data[Offset:Offset+Length].
+func WriteConcatWithIndex(c Chunker, data, index io.Writer) error {
+	enc := json.NewEncoder(index)
+	var off int64
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := data.Write(chunk); err != nil {
+			return err
+		}
+		if err := enc.Encode(IndexEntry{Offset: off, Length: len(chunk)}); err != nil {
+			return err
+		}
+		off += int64(len(chunk))
+	}
+}
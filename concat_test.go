@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func TestWriteConcatWithIndex(t *testing.T) {
+	input, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var data, index bytes.Buffer
+	if err := WriteConcatWithIndex(NewWAVChunker(bytes.NewReader(input)), &data, &index); err != nil {
+		t.Fatal(err)
+	}
+	want := readAllChunks(t, NewWAVChunker(bytes.NewReader(input)))
+
+	var got [][]byte
+	scanner := bufio.NewScanner(&index)
+	for scanner.Scan() {
+		var e IndexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, data.Bytes()[e.Offset:e.Offset+int64(e.Length)])
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d index entries, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Fatalf("chunk %d mismatch", i)
+		}
+	}
+	if n := len(bytes.Join(want, nil)); data.Len() != n {
+		t.Errorf("data holds %d bytes, want %d", data.Len(), n)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+// sideInfoSize returns the size of the Layer III side information
+// following the frame header and the optional CRC.
+func sideInfoSize(hdr []byte) int {
+	mono := hdr[3]>>6 == 3
+	mpeg1 := (hdr[1]>>3)&0x03 == 3
+	switch {
+	case mpeg1 && mono:
+		return 17
+	case mpeg1:
+		return 32
+	case mono:
+		return 9
+	default:
+		return 17
+	}
+}
+
+// crc16 updates crc with data using the CRC-16 polynomial of MPEG audio.
+func crc16(crc uint16, data []byte) uint16 {
+	for _, b := range data {
+		crc ^= uint16(b) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// frameCRC computes the CRC of a protected frame, covering the last two
+// header bytes and the side information.
+func frameCRC(frame []byte) uint16 {
+	return crc16(crc16(0xffff, frame[2:4]), frame[6:6+sideInfoSize(frame)])
+}
+
+// checkCRC reports whether the CRC of a frame matches its contents.
+// Frames without CRC protection always match, and so do Layer I and II
+// frames, whose CRC covers fields this package does not parse.
+func checkCRC(frame []byte) bool {
+	if frame[1]&0x01 == 1 || !isLayerIII(frame) {
+		return true
+	}
+	if len(frame) < 6+sideInfoSize(frame) {
+		return false
+	}
+	return frameCRC(frame) == uint16(frame[4])<<8|uint16(frame[5])
+}
+
+// silentFrame returns a frame of the format described by hdr and the given
+// length that decodes to silence: all side information is zeroed, so no
+// main data is used, and the CRC protection is turned off.
+func silentFrame(hdr []byte, length int) []byte {
+	frame := make([]byte, length)
+	copy(frame, hdr[:4])
+	frame[1] |= 0x01
+	return frame
+}
@@ -0,0 +1,54 @@
+package main
+
+import "context"
+
+// contextNexter is implemented by chunkers that can give up a Next call
+// once a context is done, like WAVChunker.
+type contextNexter interface {
+	NextContext(ctx context.Context) ([]byte, error)
+}
+
+// ContextChunker makes the Next calls of a Chunker fail with ctx.Err()
+// once ctx is done, e.g. when the client of a server streaming the chunks
+// disconnects. The chunker's own NextContext is used when it has one.
+//
+// Otherwise ctx is checked before and after every call to Next of the
+// wrapped chunker, dropping a chunk read while ctx expired. A Next blocked
+// on reading the input is not interrupted, so cancellation only takes
+// effect between reads unless the input honors deadlines or is closed.
+type ContextChunker struct {
+	Chunker
+	ctx context.Context
+	err error
+}
+
+// NewContextChunker returns a ContextChunker reading the chunks of c
+// until ctx is done.
+func NewContextChunker(ctx context.Context, c Chunker) *ContextChunker {
+	return &ContextChunker{Chunker: c, ctx: ctx}
+}
+
+// Next returns the next chunk, io.EOF when done or ctx.Err() once ctx is
+// done; later calls fail with the same error.
+func (c *ContextChunker) Next() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if cn, ok := c.Chunker.(contextNexter); ok {
+		chunk, err := cn.NextContext(c.ctx)
+		if err != nil && err == c.ctx.Err() {
+			c.err = err
+		}
+		return chunk, err
+	}
+	if err := c.ctx.Err(); err != nil {
+		c.err = err
+		return nil, err
+	}
+	chunk, err := c.Chunker.Next()
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		c.err = ctxErr
+		return nil, ctxErr
+	}
+	return chunk, err
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+)
+
+func TestContextChunker(t *testing.T) {
+	wav, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp3, err := os.ReadFile("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]func() Chunker{
+		"dumb": func() Chunker { return NewDumbChunker(bytes.NewReader(wav), 1024) },
+		"mp3":  func() Chunker { return NewMP3Chunker(bytes.NewReader(mp3), 1024, 0) },
+		"wav":  func() Chunker { return NewWAVChunker(bytes.NewReader(wav), WithChunkSize(1024)) },
+	}
+	for name, newChunker := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		c := NewContextChunker(ctx, newChunker())
+		for i := 0; i < 2; i++ {
+			if _, err := c.Next(); err != nil {
+				t.Fatalf("%s: Next() error before cancel: %v", name, err)
+			}
+		}
+
+		cancel()
+		for i := 0; i < 2; i++ {
+			if chunk, err := c.Next(); err != context.Canceled || chunk != nil {
+				t.Fatalf("%s: Next() after cancel = %d bytes, %v, want context.Canceled", name, len(chunk), err)
+			}
+		}
+		if err := c.Close(); err != nil {
+			t.Errorf("%s: Close() error: %v", name, err)
+		}
+	}
+}
+
+// cancelingReader cancels a context once read from.
+type cancelingReader struct {
+	r      *bytes.Reader
+	cancel context.CancelFunc
+}
+
+func (r cancelingReader) Read(p []byte) (int, error) {
+	r.cancel()
+	return r.r.Read(p)
+}
+
+func TestContextChunkerCancelDuringNext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := NewContextChunker(ctx, NewDumbChunker(cancelingReader{bytes.NewReader(makeAudio(4096, 0x01)), cancel}, 1024))
+	if chunk, err := c.Next(); err != context.Canceled || chunk != nil {
+		t.Fatalf("Next() = %d bytes, %v, want context.Canceled", len(chunk), err)
+	}
+}
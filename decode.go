@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONChunkDecoder reads back the chunks of the JSON output format, one
+// JSON object per line, as written by the command with -output json, by
+// WriteJSONChunks or by WriteJSONFiles, so that they can be processed like
+// the chunks of any other Chunker. Chunks are checked against the digests
+// recorded with them, if any; other fields are ignored.
+type JSONChunkDecoder struct {
+	dec   *json.Decoder
+	index int
+}
+
+// NewJSONChunkDecoder returns a new JSONChunkDecoder that reads from r.
+func NewJSONChunkDecoder(r io.Reader) *JSONChunkDecoder {
+	return &JSONChunkDecoder{dec: json.NewDecoder(bufio.NewReader(r))}
+}
+
+// Next returns the next decoded chunk or io.EOF when done.
+func (d *JSONChunkDecoder) Next() ([]byte, error) {
+	var v DataChunk
+	if err := d.dec.Decode(&v); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("chunk %d: %w", d.index, err)
+	}
+	chunk, err := base64.StdEncoding.DecodeString(v.Data)
+	if err != nil {
+		return nil, fmt.Errorf("chunk %d: %w", d.index, err)
+	}
+	if err := verifyChecksum(v, chunk); err != nil {
+		return nil, fmt.Errorf("chunk %d: %w", d.index, err)
+	}
+	d.index++
+	return chunk, nil
+}
+
+// Close implements Chunker. Closing the input is up to the caller.
+func (d *JSONChunkDecoder) Close() error {
+	return nil
+}
+
+// decodeJSONChunks writes the chunks read from the JSON output in r back
+// to back to w. With rejoinWAV the chunks must be complete WAV files,
+// which are joined into a single one holding the header of the first.
+func decodeJSONChunks(w io.Writer, r io.Reader, rejoinWAV bool) error {
+	d := NewJSONChunkDecoder(r)
+	if !rejoinWAV {
+		return writeChunksAs(rawChunkWriter{w}, d)
+	}
+	wav, _, err := joinWAVChunks(d)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(wav)
+	return err
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestDecodeJSONChunks(t *testing.T) {
+	input, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var encoded bytes.Buffer
+	if err := WriteJSONChunks(&encoded, NewDumbChunker(bytes.NewReader(input), 8192), nil); err != nil {
+		t.Fatal(err)
+	}
+	var decoded bytes.Buffer
+	if err := decodeJSONChunks(&decoded, &encoded, false); err != nil {
+		t.Fatalf("decodeJSONChunks() error: %v", err)
+	}
+	if !bytes.Equal(decoded.Bytes(), input) {
+		t.Errorf("decoded %d bytes that differ from the %d input bytes", decoded.Len(), len(input))
+	}
+}
+
+func TestDecodeJSONChunksRejoinWAV(t *testing.T) {
+	input, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var encoded bytes.Buffer
+	if err := WriteJSONChunks(&encoded, NewWAVChunker(bytes.NewReader(input), WithChunkSize(8192)), nil); err != nil {
+		t.Fatal(err)
+	}
+	var decoded bytes.Buffer
+	if err := decodeJSONChunks(&decoded, &encoded, true); err != nil {
+		t.Fatalf("decodeJSONChunks() error: %v", err)
+	}
+
+	// Only the size fields, which the chunker rewrites, may differ
+	got := decoded.Bytes()
+	dataOffset, err := wavDataOffset(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := compareMasked(got, input, []int64{4, int64(dataOffset - 4)}); err != nil {
+		t.Error(err)
+	}
+	if size := readUint32LE(got[dataOffset-4 : dataOffset]); int(size) != len(got)-dataOffset {
+		t.Errorf("data size = %d, want %d", size, len(got)-dataOffset)
+	}
+}
+
+func TestJSONChunkDecoderInvalid(t *testing.T) {
+	d := NewJSONChunkDecoder(strings.NewReader("{\"data\":\"YWJj\"}\n{\"data\":\"!!\"}\n"))
+	if chunk, err := d.Next(); err != nil || string(chunk) != "abc" {
+		t.Fatalf("Next() = %q, %v, want \"abc\"", chunk, err)
+	}
+	if _, err := d.Next(); err == nil || !strings.Contains(err.Error(), "chunk 1") {
+		t.Errorf("Next() error = %v, want an error naming chunk 1", err)
+	}
+}
+
+func TestJSONChecksum(t *testing.T) {
+	chunk := []byte("abc")
+	for _, algo := range []string{checksumSHA256, checksumCRC32} {
+		var buf bytes.Buffer
+		cw, err := newChunkWriter(outputJSON, algo, &buf)
+		if err != nil {
+			t.Fatalf("%s: newChunkWriter() error: %v", algo, err)
+		}
+		if err := cw.WriteChunk(chunk); err != nil {
+			t.Fatal(err)
+		}
+		line := buf.String()
+		if !strings.Contains(line, `"`+algo+`":`) {
+			t.Fatalf("%s: no digest in %s", algo, line)
+		}
+
+		got, err := NewJSONChunkDecoder(strings.NewReader(line)).Next()
+		if err != nil || !bytes.Equal(got, chunk) {
+			t.Fatalf("%s: Next() = %q, %v, want %q", algo, got, err, chunk)
+		}
+
+		// A different chunk under the same digest is rejected
+		tampered := strings.Replace(line, "YWJj", "YWJk", 1)
+		if _, err := NewJSONChunkDecoder(strings.NewReader(tampered)).Next(); !errors.Is(err, ErrChecksumMismatch) {
+			t.Errorf("%s: Next() of a tampered chunk error = %v, want ErrChecksumMismatch", algo, err)
+		}
+	}
+
+	// Known digests of "abc"
+	var v DataChunk
+	addChecksum(&v, checksumSHA256, chunk)
+	addChecksum(&v, checksumCRC32, chunk)
+	if want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"; v.SHA256 != want {
+		t.Errorf("sha256 = %s, want %s", v.SHA256, want)
+	}
+	if want := "352441c2"; v.CRC32 != want {
+		t.Errorf("crc32 = %s, want %s", v.CRC32, want)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// sniffLen is the number of leading bytes inspected by DetectTypeVerbose.
+const sniffLen = 12
+
+// peeker is implemented by readers that can return upcoming bytes without
+// consuming them, such as *bufio.Reader.
+type peeker interface {
+	Peek(n int) ([]byte, error)
+}
+
+// detectFileType guesses the file type from the filename extension.
+func detectFileType(filename string) string {
+	typ, _ := DetectTypeVerbose(filename, nil)
+	return typ
+}
+
+// DetectTypeVerbose guesses the file type of the named input and returns
+// the reason for the choice. If r is non-nil its leading bytes are sniffed
+// first, falling back to the filename extension and finally to the dumb
+// chunker, rather than assuming unknown input is MP3.
+//
+// Readers implementing Peek, such as *bufio.Reader, are not advanced;
+// any other reader has up to 12 bytes consumed.
+func DetectTypeVerbose(filename string, r io.Reader) (typ, reason string) {
+	if r != nil {
+		if typ, reason, ok := sniffType(sniff(r)); ok {
+			return typ, reason
+		}
+	}
+
+	if typ, ok := typeFromExtension(filename); ok {
+		return typ, "extension " + strings.ToLower(filepath.Ext(filename))
+	}
+	return "dumb", "default fallback"
+}
+
+// typeFromExtension returns the file type implied by the filename
+// extension, if it is a known one.
+func typeFromExtension(filename string) (typ string, ok bool) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".mp3":
+		return "mp3", true
+	case ".wav":
+		return "wav", true
+	case ".flac":
+		return "flac", true
+	case ".ogg", ".oga", ".opus":
+		return "ogg", true
+	case ".aac":
+		return "aac", true
+	case ".aiff", ".aif", ".aifc":
+		return "aiff", true
+	}
+	return "", false
+}
+
+// ErrUnknownType is returned when the type of an input without a file
+// name cannot be recognized from its leading bytes.
+var ErrUnknownType = errors.New("cannot detect the file type from the content, set it explicitly")
+
+// detectStreamType recognizes the type of an unnamed input, such as
+// stdin, from its leading bytes alone, since there is no extension to
+// fall back to. Readers are consumed as by DetectTypeVerbose.
+func detectStreamType(r io.Reader) (typ, reason string, err error) {
+	typ, reason, ok := sniffType(sniff(r))
+	if !ok {
+		return "", "", ErrUnknownType
+	}
+	return typ, reason, nil
+}
+
+// detectFileTypeFromContent recognizes the file type from the leading
+// bytes of r alone and seeks r back to the start, so the whole input is
+// left for the chunker. Unrecognized content is chunked by the dumb
+// chunker rather than assumed to be MP3. Inputs that cannot seek, such as
+// pipes, are detected with DetectTypeVerbose through a *bufio.Reader
+// instead, which buffers the bytes it peeks at.
+func detectFileTypeFromContent(r io.ReadSeeker) (string, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	if typ, _, ok := sniffType(head[:n]); ok {
+		return typ, nil
+	}
+	return "dumb", nil
+}
+
+// sniff returns up to sniffLen leading bytes of r.
+func sniff(r io.Reader) []byte {
+	if p, ok := r.(peeker); ok {
+		head, _ := p.Peek(sniffLen)
+		return head
+	}
+	head := make([]byte, sniffLen)
+	n, _ := io.ReadFull(r, head)
+	return head[:n]
+}
+
+// sniffType recognizes the file type from its leading bytes.
+func sniffType(head []byte) (typ, reason string, ok bool) {
+	switch {
+	case len(head) >= 12 && compareID(head[0:4], "RIFF") && compareID(head[8:12], "WAVE"):
+		return "wav", "RIFF/WAVE magic", true
+	case len(head) >= 12 && (compareID(head[0:4], "RF64") || compareID(head[0:4], "BW64")) && compareID(head[8:12], "WAVE"):
+		return "wav", string(head[0:4]) + "/WAVE magic", true
+	case len(head) >= 12 && compareID(head[0:4], "FORM") && (compareID(head[8:12], "AIFF") || compareID(head[8:12], "AIFC")):
+		return "aiff", "FORM/" + string(head[8:12]) + " magic", true
+	case bytes.HasPrefix(head, []byte("ID3")):
+		return "mp3", "ID3v2 tag", true
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return "flac", "fLaC magic", true
+	case bytes.HasPrefix(head, []byte("OggS")):
+		return "ogg", "OggS magic", true
+	}
+	if i := bytes.IndexByte(head, 0xff); i >= 0 && i+1 < len(head) && head[i+1]&0xe0 == 0xe0 {
+		if head[i+1]&0xf6 == 0xf0 {
+			// The MPEG layer is reserved, so this is AAC in ADTS framing
+			return "aac", fmt.Sprintf("ADTS sync at offset %d", i), true
+		}
+		return "mp3", fmt.Sprintf("0xFF sync at offset %d", i), true
+	}
+	return "", "", false
+}
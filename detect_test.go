@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+)
+
+func TestDetectTypeVerbose(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(16, 1))
+	mp3 := []byte{0xff, 0xfb, 0x90, 0x00, 0, 0}
+
+	tests := []struct {
+		filename string
+		input    []byte
+		typ      string
+		reason   string
+	}{
+		{"song.wav", nil, "wav", "extension .wav"},
+		{"SONG.MP3", nil, "mp3", "extension .mp3"},
+		{"song.bin", nil, "dumb", "default fallback"},
+		{"song.mp3", wav, "wav", "RIFF/WAVE magic"},
+		{"song", mp3, "mp3", "0xFF sync at offset 0"},
+		{"song", append([]byte{0, 0}, mp3...), "mp3", "0xFF sync at offset 2"},
+		{"song", []byte("ID3\x04\x00\x00\x00\x00\x00\x00"), "mp3", "ID3v2 tag"},
+		{"song.wav", []byte("not audio"), "wav", "extension .wav"},
+		{"song", []byte{0xff, 0xf1, 0x50, 0x80, 0x2e, 0x7f, 0xfc}, "aac", "ADTS sync at offset 0"},
+	}
+
+	for _, tt := range tests {
+		var typ, reason string
+		if tt.input == nil {
+			typ, reason = DetectTypeVerbose(tt.filename, nil)
+		} else {
+			typ, reason = DetectTypeVerbose(tt.filename, bytes.NewReader(tt.input))
+		}
+		if typ != tt.typ || reason != tt.reason {
+			t.Errorf("DetectTypeVerbose(%q) = %q, %q; want %q, %q", tt.filename, typ, reason, tt.typ, tt.reason)
+		}
+	}
+}
+
+func TestDetectFileTypeFromContent(t *testing.T) {
+	tests := []struct {
+		input []byte
+		typ   string
+	}{
+		{makeWAV(1, 8000, 16, makeAudio(16, 1)), "wav"},
+		{[]byte("ID3\x04\x00\x00\x00\x00\x00\x00"), "mp3"},
+		{[]byte{0xff, 0xfb, 0x90, 0x00, 0, 0}, "mp3"},
+		{[]byte("fLaC\x00\x00\x00\x22"), "flac"},
+		{[]byte("OggS\x00\x02"), "ogg"},
+		{[]byte("not audio"), "dumb"},
+		{nil, "dumb"},
+	}
+	for _, tt := range tests {
+		r := bytes.NewReader(tt.input)
+		typ, err := detectFileTypeFromContent(r)
+		if err != nil || typ != tt.typ {
+			t.Errorf("detectFileTypeFromContent(%q) = %q, %v; want %q", tt.input, typ, err, tt.typ)
+		}
+		if r.Len() != len(tt.input) {
+			t.Errorf("detectFileTypeFromContent(%q) did not seek back to the start", tt.input)
+		}
+	}
+}
+
+func TestDetectStreamType(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(16, 1))
+	r := bufio.NewReader(bytes.NewReader(wav))
+	if typ, reason, err := detectStreamType(r); err != nil || typ != "wav" || reason != "RIFF/WAVE magic" {
+		t.Fatalf("detectStreamType() = %q, %q, %v; want wav", typ, reason, err)
+	}
+	if r.Buffered() != len(wav) {
+		t.Fatal("sniffing consumed input")
+	}
+	if _, _, err := detectStreamType(bytes.NewReader([]byte("not audio"))); err != ErrUnknownType {
+		t.Fatalf("detectStreamType() error = %v, want ErrUnknownType", err)
+	}
+}
+
+func TestDetectTypeVerbosePeek(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(16, 1))
+	r := bufio.NewReader(bytes.NewReader(wav))
+	if typ, _ := DetectTypeVerbose("", r); typ != "wav" {
+		t.Fatalf("got type %q, want wav", typ)
+	}
+	if n := r.Buffered(); n != len(wav) {
+		t.Fatalf("sniffing consumed input: %d bytes buffered, want %d", n, len(wav))
+	}
+}
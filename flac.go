@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math/bits"
+)
+
+// ErrInvalidFLAC is returned when the input does not start with the fLaC
+// marker followed by a STREAMINFO metadata block.
+var ErrInvalidFLAC = errors.New("not a valid FLAC stream")
+
+const (
+	flacStreamInfoSize = 34       // size of the STREAMINFO block payload
+	flacMaxHeaderSize  = 16       // largest frame header, including its CRC-8
+	flacScanSize       = 4 << 10  // bytes inspected at once for a sync code
+	flacBufferSize     = 64 << 10 // size of the input buffer
+)
+
+// FLACChunker yields FLAC chunks made of whole frames. The first chunk
+// starts with the fLaC marker and all metadata blocks, which decoders need
+// to play the frames of the following chunks.
+//
+// FLAC frames do not store their length, so a frame is taken to end where
+// the next one starts: at a sync code followed by a frame header with a
+// valid CRC-8, provided the bytes before it end with a matching CRC-16.
+// Bytes before the first frame that do not form a frame are skipped.
+type FLACChunker struct {
+	chunkStats
+	r          *bufio.Reader
+	targetSize int
+	metrics    Metrics
+	started    bool   // the metadata blocks were read
+	meta       []byte // fLaC marker and metadata blocks, until returned
+	frame      []byte // frame read ahead that did not fit into the last chunk
+	err        error
+}
+
+// NewFLACChunker returns a new FLACChunker that reads from r.
+func NewFLACChunker(r io.Reader, chunkSize int, opts ...Option) *FLACChunker {
+	o := newOptions(opts)
+	return &FLACChunker{
+		r:          bufio.NewReaderSize(o.reader(r), flacBufferSize),
+		targetSize: chunkSize,
+		metrics:    o.metrics,
+		chunkStats: chunkStats{maxChunks: o.maxChunks},
+		err:        validateChunkSize(chunkSize),
+	}
+}
+
+// Next returns the next chunk or io.EOF when done.
+func (c *FLACChunker) Next() ([]byte, error) {
+	if c.exhausted() {
+		return nil, io.EOF
+	}
+	chunk, err := c.next()
+	c.count(chunk, err)
+	return observe(c.metrics, chunk, err)
+}
+
+// next groups whole frames into a chunk of at most the target size,
+// unless a single frame is larger.
+func (c *FLACChunker) next() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if !c.started {
+		c.started = true
+		if err := c.readMetadata(); err != nil {
+			c.err = err
+			return nil, err
+		}
+	}
+
+	chunk := c.meta
+	c.meta = nil
+	for frames := 0; ; frames++ {
+		if c.frame == nil {
+			frame, err := c.readFrame()
+			if err != nil {
+				c.err = err
+				if len(chunk) > 0 {
+					return chunk, nil
+				}
+				return nil, err
+			}
+			c.frame = frame
+		}
+		if frames > 0 && len(chunk)+len(c.frame) > c.targetSize {
+			return chunk, nil
+		}
+		chunk = append(chunk, c.frame...)
+		c.frame = nil
+	}
+}
+
+// readMetadata reads the fLaC marker and the metadata blocks up to the
+// one flagged as the last.
+func (c *FLACChunker) readMetadata() error {
+	meta := make([]byte, 4, 4+4+flacStreamInfoSize)
+	if _, err := io.ReadFull(c.r, meta); err != nil || !compareID(meta, "fLaC") {
+		return ErrInvalidFLAC
+	}
+	for first := true; ; first = false {
+		var hdr [4]byte
+		if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		size := int(hdr[1])<<16 | int(hdr[2])<<8 | int(hdr[3])
+		if first && (hdr[0]&0x7f != 0 || size != flacStreamInfoSize) {
+			return ErrInvalidFLAC
+		}
+		if len(meta)+len(hdr)+size > maxHeaderSize {
+			return errors.New("flac metadata too large")
+		}
+		meta = append(meta, hdr[:]...)
+		meta = append(meta, make([]byte, size)...)
+		if _, err := io.ReadFull(c.r, meta[len(meta)-size:]); err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		if hdr[0]&0x80 != 0 {
+			c.meta = meta
+			return nil
+		}
+	}
+}
+
+// readFrame reads the next frame, which extends up to the start of the
+// frame following it or to the end of the stream.
+func (c *FLACChunker) readFrame() ([]byte, error) {
+	n, err := c.findFrame()
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, n, n+flacScanSize)
+	io.ReadFull(c.r, frame) // peeked by findFrame
+
+	for {
+		buf, err := c.r.Peek(flacScanSize)
+		if isErrNotEOF(err) {
+			return nil, err
+		}
+		if len(buf) < 2 {
+			// The last frame ends the stream
+			frame = append(frame, buf...)
+			c.r.Discard(len(buf))
+			return frame, nil
+		}
+		i := bytes.IndexByte(buf[:len(buf)-1], 0xff)
+		switch {
+		case i < 0:
+			i = len(buf) - 1 // the last byte may start a sync code
+		case buf[i+1]&0xfe != 0xf8:
+			i++
+		case i == 0:
+			if c.frameFollows(frame) {
+				return frame, nil
+			}
+			b, _ := c.r.ReadByte()
+			frame = append(frame, b)
+			continue
+		}
+		// Consume the bytes up to the next potential sync code
+		frame = append(frame, buf[:i]...)
+		c.r.Discard(i)
+	}
+}
+
+// findFrame skips input up to the next valid frame header and returns its
+// length, or io.EOF once the stream holds no more frames.
+func (c *FLACChunker) findFrame() (int, error) {
+	for {
+		hdr, err := c.r.Peek(flacMaxHeaderSize)
+		if n, ok := flacHeaderLen(hdr); ok {
+			return n, nil
+		}
+		if isErrNotEOF(err) {
+			return 0, err
+		}
+		if len(hdr) == 0 {
+			return 0, io.EOF
+		}
+		skip := len(hdr)
+		if i := bytes.IndexByte(hdr[1:], 0xff); i >= 0 {
+			skip = i + 1
+		}
+		c.r.Discard(skip)
+	}
+}
+
+// frameFollows reports whether the input continues with a frame header
+// and frame, read so far, ends with its CRC-16.
+func (c *FLACChunker) frameFollows(frame []byte) bool {
+	hdr, _ := c.r.Peek(flacMaxHeaderSize)
+	if _, ok := flacHeaderLen(hdr); !ok || len(frame) < 2 {
+		return false
+	}
+	body := frame[:len(frame)-2]
+	return crc16(0, body) == uint16(frame[len(body)])<<8|uint16(frame[len(body)+1])
+}
+
+// flacHeaderLen returns the length of the frame header at the start of
+// hdr, including the CRC-8. ok is false unless hdr starts with a complete
+// frame header free of reserved values and with a matching CRC-8.
+func flacHeaderLen(hdr []byte) (n int, ok bool) {
+	if len(hdr) < 5 || hdr[0] != 0xff || hdr[1]&0xfe != 0xf8 {
+		return 0, false
+	}
+	blockSize, sampleRate := hdr[2]>>4, hdr[2]&0x0f
+	channels, sampleSize := hdr[3]>>4, (hdr[3]>>1)&0x07
+	if blockSize == 0 || sampleRate == 15 || channels > 10 || sampleSize == 3 || hdr[3]&0x01 != 0 {
+		return 0, false
+	}
+
+	// The frame or sample number is coded like UTF-8, in up to 7 bytes
+	coded := bits.LeadingZeros8(^hdr[4])
+	switch {
+	case coded == 0:
+		coded = 1
+	case coded == 1 || coded == 8:
+		return 0, false
+	}
+	n = 4 + coded
+	if n > len(hdr) {
+		return 0, false
+	}
+	for _, b := range hdr[5:n] {
+		if b&0xc0 != 0x80 {
+			return 0, false
+		}
+	}
+
+	// Block sizes and sample rates may follow as 8 or 16 bit values
+	switch blockSize {
+	case 6:
+		n++
+	case 7:
+		n += 2
+	}
+	switch sampleRate {
+	case 12:
+		n++
+	case 13, 14:
+		n += 2
+	}
+	if n >= len(hdr) || crc8(hdr[:n]) != hdr[n] {
+		return 0, false
+	}
+	return n + 1, true
+}
+
+// crc8 computes the CRC-8 of a FLAC frame header, with the polynomial
+// x^8 + x^2 + x + 1.
+func crc8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// Close implements Chunker. A FLACChunker holds no resources, so it is a
+// no-op; closing the input is up to the caller.
+func (c *FLACChunker) Close() error {
+	return nil
+}
+
+// IndependentChunks reports whether every chunk can be decoded in isolation.
+// Only the first chunk carries the metadata blocks, so it returns false.
+func (c *FLACChunker) IndependentChunks() bool {
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// makeFLACFrame returns a frame of 256 mono 16-bit samples stored verbatim.
+func makeFLACFrame(number int, samples []byte) []byte {
+	frame := []byte{0xff, 0xf8, 0x80, 0x08, byte(number)}
+	frame = append(frame, crc8(frame))
+	frame = append(frame, 0x02) // verbatim subframe
+	frame = append(frame, samples[:512]...)
+	crc := crc16(0, frame)
+	return append(frame, byte(crc>>8), byte(crc))
+}
+
+// makeFLAC returns a FLAC stream with a STREAMINFO and a padding block
+// followed by the given frames.
+func makeFLAC(frames [][]byte) []byte {
+	stream := []byte("fLaC")
+	stream = append(stream, 0x00, 0, 0, flacStreamInfoSize)
+	stream = append(stream, make([]byte, flacStreamInfoSize)...)
+	stream = append(stream, 0x81, 0, 0, 8) // last block: padding
+	stream = append(stream, make([]byte, 8)...)
+	return append(stream, bytes.Join(frames, nil)...)
+}
+
+func TestFLACChunker(t *testing.T) {
+	var frames [][]byte
+	for i := 0; i < 10; i++ {
+		samples := makeAudio(512, byte(i))
+		if i == 4 {
+			// A false sync code with a valid header inside the audio
+			copy(samples[100:], frames[0][:6])
+		}
+		frames = append(frames, makeFLACFrame(i, samples))
+	}
+	stream := makeFLAC(frames)
+	metaLen := len(stream) - len(bytes.Join(frames, nil))
+	frameLen := len(frames[0])
+
+	chunks := readAllChunks(t, NewFLACChunker(bytes.NewReader(stream), 2000))
+	if !bytes.Equal(bytes.Join(chunks, nil), stream) {
+		t.Fatal("chunks do not join to the input")
+	}
+	if !bytes.HasPrefix(chunks[0], stream[:metaLen]) {
+		t.Error("first chunk does not start with the metadata blocks")
+	}
+	for i, chunk := range chunks {
+		if len(chunk) > 2000 {
+			t.Errorf("chunk %d: %d bytes exceed the chunk size", i, len(chunk))
+		}
+		if i > 0 && len(chunk)%frameLen != 0 {
+			t.Errorf("chunk %d: %d bytes are not whole frames", i, len(chunk))
+		}
+	}
+	if len(chunks) != 4 {
+		t.Errorf("got %d chunks, want 4 chunks of 3 frames and one of 1", len(chunks))
+	}
+
+	// Garbage before the first frame is skipped
+	junk := append(append([]byte(nil), stream[:metaLen]...), 0xff, 0x00, 0x12)
+	junk = append(junk, stream[metaLen:]...)
+	chunks = readAllChunks(t, NewFLACChunker(bytes.NewReader(junk), 1<<20))
+	if len(chunks) != 1 || !bytes.Equal(chunks[0], stream) {
+		t.Error("garbage before the first frame not skipped")
+	}
+
+	if _, err := NewFLACChunker(bytes.NewReader([]byte("RIFF")), 2000).Next(); err != ErrInvalidFLAC {
+		t.Errorf("Next() error = %v, want ErrInvalidFLAC", err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrNoFormat is returned by WAVChunker.Format when no fmt chunk was parsed.
+var ErrNoFormat = errors.New("wav format not available")
+
+// WAV format tags
+const (
+	wavFormatPCM        = 1
+	wavFormatIEEEFloat  = 3
+	wavFormatExtensible = 0xfffe
+)
+
+// wavSubFormatSuffix is shared by the sub-format GUIDs of
+// WAVE_FORMAT_EXTENSIBLE that stand for a plain format tag, which is
+// stored in their first two bytes.
+var wavSubFormatSuffix = []byte{
+	0x00, 0x00, 0x00, 0x00, 0x10, 0x00, 0x80, 0x00, 0x00, 0xaa, 0x00, 0x38, 0x9b, 0x71,
+}
+
+// WAVFormat describes the audio format parsed from the fmt chunk.
+type WAVFormat struct {
+	AudioFormat   uint16 `json:"audioFormat"`
+	Channels      uint16 `json:"channels"`
+	SampleRate    uint32 `json:"sampleRate"`
+	BitsPerSample uint16 `json:"bitsPerSample"`
+	ByteRate      uint32 `json:"byteRate"`
+	// Planar is set when the chunks hold de-interleaved samples,
+	// see WithPlanarOutput.
+	Planar bool `json:"planar,omitempty"`
+	// Extensible is set for WAVE_FORMAT_EXTENSIBLE, whose AudioFormat is
+	// resolved from the sub-format GUID. ValidBitsPerSample and
+	// ChannelMask are only set then.
+	Extensible         bool   `json:"extensible,omitempty"`
+	ValidBitsPerSample uint16 `json:"validBitsPerSample,omitempty"`
+	ChannelMask        uint32 `json:"channelMask,omitempty"`
+}
+
+// SampleFormat identifies the encoding of a single sample.
+type SampleFormat int
+
+// Sample formats reported by WAVFormat.SampleFormat.
+const (
+	SampleFormatUnknown SampleFormat = iota
+	SampleFormatU8                   // unsigned 8-bit PCM
+	SampleFormatS16                  // signed 16-bit PCM
+	SampleFormatS24                  // signed 24-bit PCM
+	SampleFormatS32                  // signed 32-bit PCM
+	SampleFormatF32                  // 32-bit IEEE float
+	SampleFormatF64                  // 64-bit IEEE float
+)
+
+var sampleFormatNames = [...]string{
+	SampleFormatUnknown: "unknown",
+	SampleFormatU8:      "u8",
+	SampleFormatS16:     "s16",
+	SampleFormatS24:     "s24",
+	SampleFormatS32:     "s32",
+	SampleFormatF32:     "f32",
+	SampleFormatF64:     "f64",
+}
+
+// String returns the short name of the sample format, e.g. "s16".
+func (f SampleFormat) String() string {
+	if f < 0 || int(f) >= len(sampleFormatNames) {
+		return sampleFormatNames[SampleFormatUnknown]
+	}
+	return sampleFormatNames[f]
+}
+
+// parseWAVFormat decodes the payload of a fmt chunk.
+func parseWAVFormat(data []byte) (WAVFormat, bool) {
+	if len(data) < 16 {
+		return WAVFormat{}, false
+	}
+	f := WAVFormat{
+		AudioFormat:   readUint16LE(data[0:2]),
+		Channels:      readUint16LE(data[2:4]),
+		SampleRate:    readUint32LE(data[4:8]),
+		ByteRate:      readUint32LE(data[8:12]),
+		BitsPerSample: readUint16LE(data[14:16]),
+	}
+	// The extension is 22 bytes: the valid bits, the channel mask and
+	// the sub-format GUID, preceded by its size cbSize
+	if f.AudioFormat == wavFormatExtensible && len(data) >= 40 && readUint16LE(data[16:18]) >= 22 {
+		f.Extensible = true
+		f.ValidBitsPerSample = readUint16LE(data[18:20])
+		f.ChannelMask = readUint32LE(data[20:24])
+		if guid := data[24:40]; bytes.Equal(guid[2:], wavSubFormatSuffix) {
+			f.AudioFormat = readUint16LE(guid[0:2])
+		}
+	}
+	return f, true
+}
+
+// SampleFormat returns the encoding of a single sample.
+// 8-bit PCM is unsigned, wider PCM samples are signed.
+func (f WAVFormat) SampleFormat() SampleFormat {
+	switch f.AudioFormat {
+	case wavFormatPCM:
+		switch f.BitsPerSample {
+		case 8:
+			return SampleFormatU8
+		case 16:
+			return SampleFormatS16
+		case 24:
+			return SampleFormatS24
+		case 32:
+			return SampleFormatS32
+		}
+	case wavFormatIEEEFloat:
+		switch f.BitsPerSample {
+		case 32:
+			return SampleFormatF32
+		case 64:
+			return SampleFormatF64
+		}
+	}
+	return SampleFormatUnknown
+}
+
+// BlockAlign returns the size in bytes of a single sample frame,
+// i.e. one sample for every channel, or 0 if it cannot be determined.
+func (f WAVFormat) BlockAlign() int {
+	return int(f.Channels) * ((int(f.BitsPerSample) + 7) / 8)
+}
+
+// Duration returns the playback duration in seconds of n bytes of audio
+// in this format, or 0 if the format does not define a byte rate.
+func (f WAVFormat) Duration(n int) float64 {
+	if f.ByteRate == 0 {
+		return 0
+	}
+	return float64(n) / float64(f.ByteRate)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"io"
+	"io/fs"
+	"strings"
+)
+
+// Options configures a chunker created by NewChunkerFromFS.
+type Options struct {
+	Type      string   // file type; empty or "auto" detects it from the content or the file name
+	ChunkSize int      // chunk size; 0 means the default chunk size
+	Extra     []Option // options passed on to the chunker
+}
+
+// NewChunkerFromFS opens the named file in fsys and returns a chunker for it.
+// The file is closed once the chunker returns an error, including io.EOF,
+// or when it is closed explicitly.
+func NewChunkerFromFS(fsys fs.FS, name string, opts Options) (Chunker, error) {
+	chunkSize := opts.ChunkSize
+	if chunkSize == 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fileType := opts.Type
+	if fileType == "" || strings.EqualFold(fileType, "auto") {
+		fileType, err = detectFSFileType(f, name)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	c, err := NewChunker(fileType, f, chunkSize, opts.Extra...)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &fileChunker{Chunker: c, f: f}, nil
+}
+
+// detectFSFileType detects the type of the file f opened as name from its
+// content if f can seek, falling back to the extension of name for
+// unrecognized content and to the dumb chunker for unknown extensions.
+// Files that cannot seek are detected from the extension alone, with the
+// same dumb fallback.
+func detectFSFileType(f fs.File, name string) (string, error) {
+	rs, ok := f.(io.ReadSeeker)
+	if !ok {
+		return detectFileType(name), nil
+	}
+	typ, err := detectFileTypeFromContent(rs)
+	if err != nil {
+		return "", err
+	}
+	if typ == "dumb" {
+		if ext, ok := typeFromExtension(name); ok {
+			typ = ext
+		}
+	}
+	return typ, nil
+}
+
+// fileChunker closes the underlying file when chunking ends.
+type fileChunker struct {
+	Chunker
+	f      fs.File
+	closed bool
+}
+
+// Next returns the next chunk or io.EOF when done.
+func (c *fileChunker) Next() ([]byte, error) {
+	chunk, err := c.Chunker.Next()
+	if err != nil {
+		c.Close()
+	}
+	return chunk, err
+}
+
+// Close closes the chunker and the underlying file.
+// Safe to call multiple times.
+func (c *fileChunker) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	err := c.Chunker.Close()
+	if ferr := c.f.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"testing/fstest"
+)
+
+func TestNewChunkerFromFS(t *testing.T) {
+	data, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{"audio/sample.wav": {Data: data}}
+
+	c, err := NewChunkerFromFS(fsys, "audio/sample.wav", Options{})
+	if err != nil {
+		t.Fatalf("NewChunkerFromFS() error: %v", err)
+	}
+	got := readAllChunks(t, c)
+	if err := c.(interface{ Close() error }).Close(); err != nil {
+		t.Errorf("Close() error: %v", err)
+	}
+
+	file, err := os.Open("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	want := readAllChunks(t, NewWAVChunker(file))
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d chunks, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Fatalf("chunk %d mismatch", i)
+		}
+	}
+}
+
+func TestNewChunkerFromFSMissing(t *testing.T) {
+	if _, err := NewChunkerFromFS(fstest.MapFS{}, "missing.wav", Options{}); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+}
+
+func TestNewChunkerFromFSDetectsContent(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(4000, 3))
+	fsys := fstest.MapFS{"recording": {Data: wav}, "notes.txt": {Data: []byte("plain text")}}
+
+	c, err := NewChunkerFromFS(fsys, "recording", Options{})
+	if err != nil {
+		t.Fatalf("NewChunkerFromFS() error: %v", err)
+	}
+	chunks := readAllChunks(t, c)
+	if len(chunks) != 1 || !bytes.Equal(chunks[0], wav) {
+		t.Errorf("extension-less WAV file not chunked as WAV")
+	}
+
+	c, err = NewChunkerFromFS(fsys, "notes.txt", Options{})
+	if err != nil {
+		t.Fatalf("NewChunkerFromFS() error: %v", err)
+	}
+	if _, ok := c.(*fileChunker).Chunker.(*DumbChunker); !ok {
+		t.Errorf("unknown content chunked by %T, want *DumbChunker", c.(*fileChunker).Chunker)
+	}
+	c.Close()
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"sync"
+)
+
+// ErrCompressedSize is returned by WAVChunker.NextSize when chunks are
+// compressed, as their size is only known once compressed by Next.
+var ErrCompressedSize = errors.New("size of a compressed chunk is not known before Next")
+
+// gzipPools holds reusable gzip writers for every compression level,
+// indexed by the level minus gzip.HuffmanOnly.
+var gzipPools [gzip.BestCompression - gzip.HuffmanOnly + 1]sync.Pool
+
+// validGzipLevel reports whether level is a compression level gzip accepts.
+func validGzipLevel(level int) bool {
+	return level >= gzip.HuffmanOnly && level <= gzip.BestCompression
+}
+
+// gzipChunk returns chunk compressed as a complete gzip stream, so it can
+// be decompressed on its own. level must be a valid compression level.
+func gzipChunk(chunk []byte, level int) []byte {
+	var buf bytes.Buffer
+	buf.Grow(len(chunk)/2 + 64)
+	pool := &gzipPools[level-gzip.HuffmanOnly]
+	w, _ := pool.Get().(*gzip.Writer)
+	if w == nil {
+		w, _ = gzip.NewWriterLevel(&buf, level)
+	} else {
+		w.Reset(&buf)
+	}
+	// Writes to a bytes.Buffer never fail
+	w.Write(chunk)
+	w.Close()
+	pool.Put(w)
+	return buf.Bytes()
+}
+
+// compress gzips chunk if a compression level is configured.
+func (c *WAVChunker) compress(chunk []byte) []byte {
+	if c.gzipLevel == gzip.NoCompression {
+		return chunk
+	}
+	return gzipChunk(chunk, c.gzipLevel)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestWAVChunkerGzip(t *testing.T) {
+	wav := makeWAV(2, 44100, 16, makeAudio(50000, 0x3d))
+
+	chunks := readAllChunks(t, NewWAVChunker(bytes.NewReader(wav), WithGzipLevel(gzip.BestSpeed)))
+	plain := readAllChunks(t, NewWAVChunker(bytes.NewReader(wav)))
+	if len(chunks) != len(plain) {
+		t.Fatalf("got %d compressed chunks, want %d", len(chunks), len(plain))
+	}
+
+	var joined []byte
+	for i, chunk := range chunks {
+		zr, err := gzip.NewReader(bytes.NewReader(chunk))
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		data, err := io.ReadAll(zr)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		if !bytes.Equal(data, plain[i]) {
+			t.Fatalf("chunk %d: decompressed chunk differs from the uncompressed one", i)
+		}
+		offset, err := wavDataOffset(data)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		if i == 0 {
+			joined = append(joined, data[:offset]...)
+		}
+		joined = append(joined, data[offset:]...)
+	}
+	copy(joined[4:8], writeUint32LE(uint32(len(joined)-8)))
+	copy(joined[40:44], writeUint32LE(uint32(len(joined)-44)))
+	if !bytes.Equal(joined, wav) {
+		t.Error("decompressed chunks do not reassemble the input")
+	}
+}
+
+func TestWAVChunkerGzipNextSize(t *testing.T) {
+	wav := makeWAV(2, 44100, 16, makeAudio(50000, 0x3d))
+	c := NewWAVChunker(bytes.NewReader(wav), WithGzipLevel(gzip.BestSpeed))
+	if n, err := c.NextSize(); err != ErrCompressedSize {
+		t.Fatalf("NextSize() = %d, %v, want ErrCompressedSize", n, err)
+	}
+	if _, err := c.Next(); err != nil {
+		t.Fatalf("Next() after NextSize() error: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Segment is a single media segment referenced by an HLS playlist.
+type Segment struct {
+	Filename string
+	Duration float64 // seconds
+}
+
+// WriteHLSPlaylist writes an HLS media playlist (.m3u8) referencing segments in order.
+func WriteHLSPlaylist(w io.Writer, segments []Segment) error {
+	target := 0.0
+	for _, s := range segments {
+		target = math.Max(target, s.Duration)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("#EXTM3U\n")
+	buf.WriteString("#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&buf, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(target)))
+	buf.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	for _, s := range segments {
+		fmt.Fprintf(&buf, "#EXTINF:%.6f,\n%s\n", s.Duration, s.Filename)
+	}
+	buf.WriteString("#EXT-X-ENDLIST\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteHLSPlaylist(t *testing.T) {
+	// 1 second of 8 kHz 16-bit mono audio per full chunk
+	wav := makeWAV(1, 8000, 16, makeAudio(40000, 0x10))
+	chunker := NewWAVChunker(bytes.NewReader(wav))
+	chunker.targetSize = 44 + 16000
+	defer chunker.Close()
+
+	var segments []Segment
+	for i, chunk := range readAllChunks(t, chunker) {
+		format, err := chunker.Format()
+		if err != nil {
+			t.Fatalf("Format() error: %v", err)
+		}
+		segments = append(segments, Segment{
+			Filename: fmt.Sprintf("part-%05d.wav", i),
+			Duration: format.Duration(len(chunk) - 44),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHLSPlaylist(&buf, segments); err != nil {
+		t.Fatalf("WriteHLSPlaylist() error: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"#EXTM3U",
+		"#EXT-X-VERSION:3",
+		"#EXT-X-TARGETDURATION:1",
+		"#EXT-X-MEDIA-SEQUENCE:0",
+		"#EXTINF:1.000000,",
+		"part-00000.wav",
+		"#EXTINF:1.000000,",
+		"part-00001.wav",
+		"#EXTINF:0.500000,",
+		"part-00002.wav",
+		"#EXT-X-ENDLIST",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Fatalf("playlist mismatch:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
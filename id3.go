@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrInvalidID3Tag is returned when the ID3v2 tag at the start of an MP3
+// stream is malformed, such as an extended header not fitting into it.
+var ErrInvalidID3Tag = errors.New("invalid ID3v2 tag")
+
+const (
+	id3v2HeaderSize    = 10
+	id3v2FlagExtHeader = 0x40
+	id3v2FlagFooter    = 0x10
+)
+
+// syncsafe decodes a big-endian integer storing 7 bits per byte, as used
+// for ID3v2 sizes. ok is false if any byte has its high bit set.
+func syncsafe(b []byte) (n int, ok bool) {
+	for _, v := range b {
+		if v&0x80 != 0 {
+			return 0, false
+		}
+		n = n<<7 | int(v)
+	}
+	return n, true
+}
+
+// readID3v2 reads the ID3v2 tag at the start of the stream, if any.
+// Bytes that do not form a tag header are left unread for the frame scanner.
+func (c *MP3Chunker) readID3v2() error {
+	hdr, err := c.r.Peek(id3v2HeaderSize)
+	if isErrNotEOF(err) {
+		return err
+	}
+	n := len(hdr)
+
+	var size int
+	ok := false
+	if n == id3v2HeaderSize {
+		size, ok = syncsafe(hdr[6:10])
+		if hdr[3] == 0xff || hdr[4] == 0xff {
+			ok = false // version bytes are never 0xff
+		}
+	}
+	if n < 3 || !bytes.Equal(hdr[:3], []byte("ID3")) || (n == id3v2HeaderSize && !ok) {
+		return nil
+	}
+	if n < id3v2HeaderSize {
+		return io.ErrUnexpectedEOF
+	}
+
+	if hdr[5]&id3v2FlagFooter != 0 {
+		size += id3v2HeaderSize
+	}
+	// The size is untrusted, so the body is read as it comes instead of
+	// into a buffer of the declared size
+	tag := append([]byte(nil), hdr...)
+	c.r.Discard(id3v2HeaderSize)
+	body, err := io.ReadAll(io.LimitReader(c.r, int64(size)))
+	if err != nil {
+		return err
+	}
+	if len(body) < size {
+		return io.ErrUnexpectedEOF
+	}
+	tag = append(tag, body...)
+	if tag[5]&id3v2FlagExtHeader != 0 && !validExtHeader(tag[3], tag[id3v2HeaderSize:]) {
+		return ErrInvalidID3Tag
+	}
+	c.id3 = tag
+	return nil
+}
+
+// validExtHeader reports whether the extended header at the start of the
+// tag body fits into it. Its size is syncsafe and includes the size field
+// itself since ID3v2.4; ID3v2.3 stores a plain size excluding the field.
+func validExtHeader(version byte, body []byte) bool {
+	if len(body) < 4 {
+		return false
+	}
+	var size int
+	if version >= 4 {
+		n, ok := syncsafe(body[:4])
+		if !ok {
+			return false
+		}
+		size = n
+	} else {
+		size = int(binary.BigEndian.Uint32(body[:4])) + 4
+	}
+	return size >= 4 && size <= len(body)
+}
+
+// ID3v2 returns the raw leading ID3v2 tag of the stream, including its
+// header, or nil if there is none. It is available after the first call
+// to Next.
+func (c *MP3Chunker) ID3v2() []byte {
+	return c.id3
+}
+
+// id3v1Size is the size of an ID3v1 tag, which ends the stream.
+const id3v1Size = 128
+
+// readID3v1 checks whether the 'T' just read starts an ID3v1 tag ending
+// the stream. If so the tag is kept and ok is true, otherwise the bytes
+// peeked at are left unread for the frame scanner.
+func (c *MP3Chunker) readID3v1() (ok bool, err error) {
+	rest, err := c.r.Peek(id3v1Size) // one more byte to probe for EOF
+	if isErrNotEOF(err) {
+		return false, err
+	}
+	if len(rest) != id3v1Size-1 || !bytes.Equal(rest[:2], []byte("AG")) {
+		return false, nil
+	}
+	c.id3v1 = append([]byte{'T'}, rest...)
+	c.r.Discard(len(rest))
+	return true, nil
+}
+
+// TrailingTag returns the raw ID3v1 tag ending the stream, or nil if there
+// is none. It is available once Next has returned io.EOF.
+func (c *MP3Chunker) TrailingTag() []byte {
+	return c.id3v1
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/fs"
+)
+
+// DataChunk is a single chunk in the JSON output. The hex digests of the
+// chunk are only set when requested with -checksum.
+type DataChunk struct {
+	Data   string `json:"data"`
+	SHA256 string `json:"sha256,omitempty"`
+	CRC32  string `json:"crc32,omitempty"`
+}
+
+// FileDataChunk is a chunk in the JSON output of several files, tagged with
+// the name of its file and its index within the file.
+type FileDataChunk struct {
+	File  string `json:"file"`
+	Index int    `json:"index"`
+	DataChunk
+}
+
+// ChunkMetaFunc returns extra fields to include in the JSON object of the
+// chunk with the given index.
+type ChunkMetaFunc func(index int, chunk []byte) map[string]any
+
+// WriteJSONChunks writes every chunk of c to w as a JSON object holding the
+// base64-encoded chunk in its "data" field, one object per line. If meta is
+// non-nil, the fields it returns are merged into every object; a "data"
+// field returned by meta is ignored.
+func WriteJSONChunks(w io.Writer, c Chunker, meta ChunkMetaFunc) error {
+	return writeChunksAs(&jsonChunkWriter{enc: json.NewEncoder(w), meta: meta}, c)
+}
+
+// FileError records the failure to chunk one of several files.
+type FileError struct {
+	Name string
+	Err  error
+}
+
+func (e FileError) Error() string {
+	return e.Name + ": " + e.Err.Error()
+}
+
+func (e FileError) Unwrap() error {
+	return e.Err
+}
+
+// errorObject is written to the JSON output for a file that failed.
+type errorObject struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// WriteJSONFiles writes the chunks of the named files in fsys to w like
+// WriteJSONChunks, tagging every object with "file" and "index" fields. A
+// file that cannot be chunked does not stop the others: its error is
+// collected and, if interleave is set, also written to w as an object with
+// "file" and "error" fields. Chunks emitted before the failure are kept.
+// The error is that of writing to w, which stops the remaining files.
+func WriteJSONFiles(w io.Writer, fsys fs.FS, names []string, opts Options, interleave bool) ([]FileError, error) {
+	open := func(name string) (Chunker, error) {
+		return NewChunkerFromFS(fsys, name, opts)
+	}
+	return writeFiles(&jsonChunkWriter{enc: json.NewEncoder(w)}, names, open, nil, interleave)
+}
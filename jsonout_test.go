@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWriteJSONChunks(t *testing.T) {
+	input := makeAudio(2500, 0x11)
+
+	var plain bytes.Buffer
+	if err := WriteJSONChunks(&plain, NewDumbChunker(bytes.NewReader(input), 1024), nil); err != nil {
+		t.Fatal(err)
+	}
+	want := base64.StdEncoding.EncodeToString(input[:1024])
+	if line, _ := plain.ReadString('\n'); line != `{"data":"`+want+`"}`+"\n" {
+		t.Errorf("got first line %q", line)
+	}
+
+	var out bytes.Buffer
+	meta := func(index int, chunk []byte) map[string]any {
+		return map[string]any{"ts": 1700000000 + index, "size": len(chunk), "data": "ignored"}
+	}
+	if err := WriteJSONChunks(&out, NewDumbChunker(bytes.NewReader(input), 1024), meta); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []byte
+	scanner := bufio.NewScanner(&out)
+	for i := 0; scanner.Scan(); i++ {
+		var obj struct {
+			Data string `json:"data"`
+			TS   int    `json:"ts"`
+			Size int    `json:"size"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &obj); err != nil {
+			t.Fatal(err)
+		}
+		data, err := base64.StdEncoding.DecodeString(obj.Data)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		if obj.TS != 1700000000+i || obj.Size != len(data) {
+			t.Errorf("chunk %d: got ts %d, size %d", i, obj.TS, obj.Size)
+		}
+		got = append(got, data...)
+	}
+	if !bytes.Equal(got, input) {
+		t.Error("data fields do not reassemble to the input")
+	}
+}
+
+func TestWriteJSONFiles(t *testing.T) {
+	good := makeWAV(1, 8000, 16, makeAudio(20000, 0x42))
+	fsys := fstest.MapFS{
+		"good.wav": {Data: good},
+		"bad.wav":  {Data: []byte("not a wav file")},
+	}
+
+	var out bytes.Buffer
+	errs, err := WriteJSONFiles(&out, fsys, []string{"bad.wav", "good.wav", "missing.wav"}, Options{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(errs) != 2 || errs[0].Name != "bad.wav" || errs[1].Name != "missing.wav" {
+		t.Fatalf("got errors %v, want bad.wav and missing.wav", errs)
+	}
+	if !errors.Is(errs[1], fs.ErrNotExist) {
+		t.Errorf("missing.wav: got %v, want %v", errs[1].Err, fs.ErrNotExist)
+	}
+
+	var chunks, errObjs int
+	scanner := bufio.NewScanner(&out)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var obj struct {
+			File  string `json:"file"`
+			Data  string `json:"data"`
+			Error string `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &obj); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case obj.Error != "":
+			errObjs++
+			if obj.File == "good.wav" || obj.Data != "" {
+				t.Errorf("unexpected error object %+v", obj)
+			}
+		case obj.File == "good.wav":
+			chunks++
+		default:
+			t.Errorf("unexpected object %+v", obj)
+		}
+	}
+	if want := len(readAllChunks(t, NewWAVChunker(bytes.NewReader(good)))); chunks != want {
+		t.Errorf("got %d chunks of good.wav, want %d", chunks, want)
+	}
+	if errObjs != 2 {
+		t.Errorf("got %d error objects, want 2", errObjs)
+	}
+}
+
+// failingWriter fails every write with err.
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestWriteJSONFilesWriteError(t *testing.T) {
+	errBroken := errors.New("broken")
+	fsys := fstest.MapFS{"bad.wav": {Data: []byte("not a wav file")}}
+	errs, err := WriteJSONFiles(failingWriter{errBroken}, fsys, []string{"bad.wav", "missing.wav"}, Options{}, true)
+	if err != errBroken {
+		t.Errorf("got error %v, want %v", err, errBroken)
+	}
+	if len(errs) != 1 || errs[0].Name != "bad.wav" {
+		t.Errorf("got errors %v, want bad.wav only", errs)
+	}
+}
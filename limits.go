@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Size limits enforced by the package, exported so that callers can
+// validate their configuration against the same bounds.
+const (
+	// DefaultChunkSize is the chunk size used when none is configured.
+	DefaultChunkSize = defaultChunkSize
+	// MinChunkSize and MaxChunkSize bound the chunk size accepted by
+	// NewChunker and ParseSize.
+	MinChunkSize = minChunkSize
+	MaxChunkSize = maxBlockSize
+	// MaxMetadataChunkSize is the largest non-data RIFF chunk a WAVChunker
+	// accepts in the header.
+	MaxMetadataChunkSize = maxMetadataChunkSize
+	// MaxHeaderSize is the largest WAV header a WAVChunker accepts.
+	MaxHeaderSize = maxHeaderSize
+	// MaxFrameSize is the largest MP3 frame size.
+	MaxFrameSize = maxFrameSize
+	// MaxReservoir is the largest MP3 bit reservoir carried between chunks;
+	// larger reservoir sizes are clamped.
+	MaxReservoir = maxReservoir
+)
+
+// ErrInvalidChunkSize is returned by NewChunker for a chunk size outside
+// [MinChunkSize, MaxChunkSize], and by Next of a chunker whose constructor
+// was passed one. WithChunkSize falls back to DefaultChunkSize instead.
+var ErrInvalidChunkSize = errors.New("invalid chunk size")
+
+// validateChunkSize reports a chunk size outside [MinChunkSize, MaxChunkSize].
+func validateChunkSize(n int) error {
+	if n < MinChunkSize || n > MaxChunkSize {
+		return fmt.Errorf("%w %d: must be between %d and %d", ErrInvalidChunkSize, n, MinChunkSize, MaxChunkSize)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestLimits(t *testing.T) {
+	tests := []struct {
+		name          string
+		got, internal int
+	}{
+		{"DefaultChunkSize", DefaultChunkSize, defaultChunkSize},
+		{"MinChunkSize", MinChunkSize, minChunkSize},
+		{"MaxChunkSize", MaxChunkSize, maxBlockSize},
+		{"MaxMetadataChunkSize", MaxMetadataChunkSize, maxMetadataChunkSize},
+		{"MaxHeaderSize", MaxHeaderSize, maxHeaderSize},
+		{"MaxFrameSize", MaxFrameSize, maxFrameSize},
+		{"MaxReservoir", MaxReservoir, maxReservoir},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.internal {
+			t.Errorf("%s = %d, want %d", tt.name, tt.got, tt.internal)
+		}
+	}
+
+	for _, fileType := range SupportedTypes() {
+		for _, size := range []int{MinChunkSize, DefaultChunkSize, MaxChunkSize} {
+			if _, err := NewChunker(fileType, nil, size); err != nil {
+				t.Errorf("%s: NewChunker(%d) error: %v", fileType, size, err)
+			}
+		}
+		for _, size := range []int{0, MinChunkSize - 1, MaxChunkSize + 1} {
+			if _, err := NewChunker(fileType, nil, size); err == nil {
+				t.Errorf("%s: NewChunker(%d) succeeded, want error", fileType, size)
+			}
+		}
+	}
+
+	if n, err := ParseSize("1G"); err != nil || n != MaxChunkSize {
+		t.Errorf("ParseSize(1G) = %d, %v; want %d", n, err, MaxChunkSize)
+	}
+	if c := NewMP3Chunker(nil, DefaultChunkSize, MaxReservoir+1); c.reservoirCap != MaxReservoir {
+		t.Errorf("reservoir clamped to %d, want %d", c.reservoirCap, MaxReservoir)
+	}
+
+	// The chunkers created directly fail on Next instead
+	mp3 := NewMP3Chunker(bytes.NewReader(nil), MinChunkSize-1, 0)
+	chunkers := map[string]Chunker{
+		"dumb": NewDumbChunker(bytes.NewReader(nil), MinChunkSize-1),
+		"mp3":  mp3,
+		"wav":  NewWAVFromPCM(bytes.NewReader(nil), 44100, 2, 16, MaxChunkSize+1),
+		"flac": NewFLACChunker(bytes.NewReader(nil), MinChunkSize-1),
+		"ogg":  NewOggChunker(bytes.NewReader(nil), 0),
+		"adts": NewADTSChunker(bytes.NewReader(nil), -1),
+		"aiff": NewAIFFChunker(bytes.NewReader(nil), MaxChunkSize+1),
+		"pcm":  NewPCMChunker(bytes.NewReader(nil), MinChunkSize-1, WAVFormat{Channels: 1, SampleRate: 8000, BitsPerSample: 16}),
+	}
+	for name, c := range chunkers {
+		if _, err := c.Next(); !errors.Is(err, ErrInvalidChunkSize) {
+			t.Errorf("%s: Next() error %v, want ErrInvalidChunkSize", name, err)
+		}
+	}
+	if err := mp3.ClearError(); err != ErrUnrecoverable {
+		t.Errorf("mp3: ClearError() = %v, want ErrUnrecoverable", err)
+	}
+	mp3.Reset(bytes.NewReader(nil))
+	if _, err := mp3.Next(); !errors.Is(err, ErrInvalidChunkSize) {
+		t.Errorf("mp3: Next() after Reset error %v, want ErrInvalidChunkSize", err)
+	}
+
+	p := NewParallelDumbChunker(bytes.NewReader(nil), 0, MinChunkSize-1)
+	if _, err := p.ChunkAt(0); !errors.Is(err, ErrInvalidChunkSize) {
+		t.Errorf("parallel: ChunkAt() error %v, want ErrInvalidChunkSize", err)
+	}
+	if err := p.ReadParallel(2, func([]byte) error { return nil }); !errors.Is(err, ErrInvalidChunkSize) {
+		t.Errorf("parallel: ReadParallel() error %v, want ErrInvalidChunkSize", err)
+	}
+}
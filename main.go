@@ -1,85 +1,297 @@
 package main
 
 import (
-	"encoding/base64"
-	"encoding/json"
+	"bufio"
+	"compress/gzip"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 )
 
-type DataChunk struct {
-	Data string `json:"data"`
-}
-
 func main() {
-	var blockSize int
+	blockSize := sizeFlag(8192)
 	var fileType string
+	var verbose bool
+	var concat string
+	var gzipLevel int
+	var output, checksum string
+	var decode, rejoinWAV bool
+	var split, stats, hls, sidecars bool
+	var interleaveErrors bool
+	var outDir, prefix string
+	var width, parallel, limit int
+
+	flag.Var(&blockSize, "b", "block size for chunking, e.g. 8192, 64k or 1M")
+	types := SupportedTypes()
 
-	flag.IntVar(&blockSize, "b", 8192, "block size for chunking")
-	flag.StringVar(&fileType, "type", "auto", "file type: mp3, wav, dumb, or auto")
+	flag.StringVar(&fileType, "type", "auto", "file type: "+strings.Join(types, ", ")+", or auto")
+
+	flag.BoolVar(&verbose, "verbose", false, "report why the file type was chosen")
+	flag.IntVar(&gzipLevel, "gzip", gzip.NoCompression, "gzip every WAV chunk at this compression level, from -2 (Huffman only) to 9")
+	flag.StringVar(&output, "output", outputJSON, "output format: json (base64 in JSON lines), raw (chunks back to back) or framed (4-byte big-endian length before every chunk)")
+	flag.StringVar(&checksum, "checksum", "", "add the sha256 or crc32 digest of every chunk to the JSON output")
+	flag.BoolVar(&decode, "decode", false, "turn the JSON output read from the file back into the original bytes")
+	flag.BoolVar(&rejoinWAV, "rejoin-wav", false, "with -decode, join complete WAV chunks into a single WAV file")
+	flag.StringVar(&concat, "concat", "", "write the chunks back to back to this file and their index to the file with .idx appended, instead of JSON to stdout")
+	flag.BoolVar(&split, "split", false, "write every chunk to its own numbered file in -outdir instead of to stdout")
+	flag.StringVar(&outDir, "outdir", ".", "with -split, the directory of the chunk files, created if missing")
+	flag.StringVar(&prefix, "prefix", "part", "with -split, the name prefix of the chunk files")
+	flag.IntVar(&width, "width", 5, "with -split, the number of digits the chunk index is zero-padded to")
+	flag.BoolVar(&sidecars, "sidecars", false, "with -split, write the offset, length and format of every chunk as JSON to a file named like the chunk with a .json extension")
+	flag.BoolVar(&hls, "hls", false, "with -split, also write an HLS playlist of the chunk files, named after -prefix, to -outdir")
+	flag.IntVar(&parallel, "parallel", 1, "read the chunks of a dumb-chunked file with this many workers")
+	flag.BoolVar(&stats, "stats", false, "print the number of chunks and bytes, and of MP3 frames, to stderr once done")
+	flag.IntVar(&limit, "limit", 0, "stop after this many chunks per file, 0 for no limit")
+	flag.BoolVar(&interleaveErrors, "interleave-errors", false, "with several files, also write an error record for every file that fails to the JSON output")
 
 	flag.Parse()
 
-	if flag.NArg() < 1 {
-		fmt.Fprintf(os.Stderr, "Usage: %s [-b blocksize] [-type mp3|wav|dumb|auto] <file>\n", os.Args[0])
+	// Read stdin when the file is "-" or omitted and stdin is not a terminal
+	names := flag.Args()
+	if len(names) == 0 {
+		if stdinIsTerminal() {
+			fmt.Fprintf(os.Stderr, "Usage: %s [-b blocksize] [-type %s|auto] [-verbose] [-gzip level] [-output json|raw|framed] [-checksum sha256|crc32] [-concat datafile] [-split [-outdir dir] [-prefix name] [-width n] [-sidecars] [-hls]] [-parallel n] [-stats] [-limit n] [-interleave-errors] [-decode [-rejoin-wav]] <file|->...\n", os.Args[0], strings.Join(types, "|"))
+			os.Exit(1)
+		}
+		names = []string{"-"}
+	}
+	if len(names) > 1 && (decode || concat != "" || split) {
+		fmt.Fprintln(os.Stderr, "Error: -decode, -concat and -split take a single file")
+		os.Exit(1)
+	}
+	if (hls || sidecars) && !split {
+		fmt.Fprintln(os.Stderr, "Error: -hls and -sidecars need -split")
 		os.Exit(1)
 	}
 
-	filename := flag.Arg(0)
-
-	file, err := os.Open(filename)
+	stdout := bufio.NewWriter(os.Stdout)
+	cw, err := newChunkWriter(output, checksum, stdout)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-	defer file.Close()
 
-	// Auto-detect file type if not specified
-	if fileType == "auto" {
-		fileType = detectFileType(filename)
-	}
-
-	var chunker Chunker
-	switch strings.ToLower(fileType) {
-	case "mp3":
-		chunker = NewMP3Chunker(file, blockSize, 2048)
-	case "wav":
-		chunker = NewWAVChunker(file)
-	case "dumb":
-		chunker = NewDumbChunker(file, blockSize)
-	default:
-		fmt.Fprintf(os.Stderr, "Unsupported file type: %s\n", fileType)
-		os.Exit(1)
+	if decode {
+		input, err := openInput(names[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening file: %v\n", err)
+			os.Exit(1)
+		}
+		defer input.Close()
+		if err = decodeJSONChunks(stdout, bufio.NewReader(input), rejoinWAV); err == nil {
+			err = stdout.Flush()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error decoding chunks: %v\n", err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	for {
-		chunk, err := chunker.Next()
-		if err == io.EOF {
-			break
+	if len(names) == 1 {
+		chunker, detected, err := openChunker(names[0], fileType, int(blockSize), verbose, WithGzipLevel(gzipLevel), WithMaxChunks(limit))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer chunker.Close()
+
+		var source any = chunker // what the stats are taken from
+		switch {
+		case concat != "":
+			err = writeConcatFiles(chunker, concat, concat+".idx")
+		case split:
+			playlist := ""
+			if hls {
+				playlist = prefix + ".m3u8"
+			}
+			err = writeSplitFiles(chunker, outDir, prefix, splitExtension(detected, gzipLevel), width, playlist, sidecars)
+		case parallel > 1 && detected == "dumb" && names[0] != "-" && limit == 0:
+			if source, err = writeParallelDumb(cw, names[0], int(blockSize), parallel); err == nil {
+				err = stdout.Flush()
+			}
+		default:
+			if err = writeChunksAs(cw, chunker); err == nil {
+				err = stdout.Flush()
+			}
 		}
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error chunking file: %v\n", err)
 			os.Exit(1)
 		}
+		if stats {
+			printStats(names[0], source)
+		}
+		return
+	}
+
+	// A failed file does not stop the others, its chunks written so far
+	// are kept and, with -interleave-errors, followed by an error record in
+	// the JSON output
+	open := func(name string) (Chunker, error) {
+		c, _, err := openChunker(name, fileType, int(blockSize), verbose, WithGzipLevel(gzipLevel), WithMaxChunks(limit))
+		return c, err
+	}
+	var done func(string, Chunker)
+	if stats {
+		done = func(name string, c Chunker) { printStats(name, c) }
+	}
+	errs, err := writeFiles(cw, names, open, done, interleaveErrors)
+	if err == nil {
+		err = stdout.Flush()
+	}
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "Error chunking file: %v\n", e)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+		os.Exit(1)
+	}
+	if len(errs) > 0 {
+		os.Exit(1)
+	}
+}
+
+// openInput opens the named file, or stdin for "-".
+func openInput(name string) (*os.File, error) {
+	if name == "-" {
+		return os.Stdin, nil
+	}
+	return os.Open(name)
+}
 
-		dataChunk := DataChunk{
-			Data: base64.StdEncoding.EncodeToString(chunk),
+// openChunker opens the named file, or stdin for "-", and returns a chunker
+// of the given type for it, which closes the file when closed, along with
+// the type. The type "auto" is detected from the file name and content, or
+// the content alone for stdin.
+func openChunker(name, fileType string, chunkSize int, verbose bool, opts ...Option) (Chunker, string, error) {
+	file, err := openInput(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("opening file: %w", err)
+	}
+	input := bufio.NewReader(file)
+
+	if fileType == "auto" {
+		var reason string
+		if name == "-" {
+			fileType, reason, err = detectStreamType(input)
+			if err != nil {
+				file.Close()
+				return nil, "", fmt.Errorf("reading stdin: %w with -type", err)
+			}
+		} else {
+			fileType, reason = DetectTypeVerbose(name, input)
 		}
-		if err := json.NewEncoder(os.Stdout).Encode(dataChunk); err != nil {
-			fmt.Fprintf(os.Stderr, "Error encoding JSON: %v\n", err)
-			os.Exit(1)
+		if verbose {
+			fmt.Fprintf(os.Stderr, "Detected type %s of %s: %s\n", fileType, name, reason)
 		}
 	}
+
+	c, err := NewChunker(fileType, input, chunkSize, opts...)
+	if err != nil {
+		file.Close()
+		return nil, "", fmt.Errorf("creating chunker: %w", err)
+	}
+	return &fileChunker{Chunker: c, f: file}, fileType, nil
+}
+
+// writeConcatFiles writes the chunks of c to the data file and their
+// index to the index file.
+func writeConcatFiles(c Chunker, dataPath, indexPath string) error {
+	data, err := os.Create(dataPath)
+	if err != nil {
+		return err
+	}
+	defer data.Close()
+	index, err := os.Create(indexPath)
+	if err != nil {
+		return err
+	}
+	defer index.Close()
+
+	w := bufio.NewWriter(data)
+	if err := WriteConcatWithIndex(c, w, index); err != nil {
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return index.Close()
+}
+
+// writeSplitFiles writes every chunk of c to its own file in dir, see
+// SplitSink, along with an HLS playlist of them named playlist, if set,
+// and the ChunkInfo of every chunk with sidecars.
+func writeSplitFiles(c Chunker, dir, prefix, ext string, width int, playlist string, sidecars bool) error {
+	sink, err := NewSplitSink(dir, prefix, ext, width)
+	if err != nil {
+		return err
+	}
+	if playlist != "" {
+		sink.WithHLSPlaylist(playlist)
+	}
+	if sidecars {
+		sink.WithSidecars()
+	}
+	return Drain(c, sink)
 }
 
-func detectFileType(filename string) string {
-	if strings.HasSuffix(strings.ToLower(filename), ".mp3") {
-		return "mp3"
-	} else if strings.HasSuffix(strings.ToLower(filename), ".wav") {
-		return "wav"
+// writeParallelDumb writes the fixed-size chunks of the named file to cw,
+// reading them with the given number of workers, and returns the chunker
+// used.
+func writeParallelDumb(cw chunkWriter, name string, chunkSize, workers int) (*ParallelDumbChunker, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
 	}
-	return "mp3" // default to mp3 for unknown extensions
+	c := NewParallelDumbChunker(f, fi.Size(), chunkSize)
+	return c, c.ReadParallel(workers, cw.WriteChunk)
+}
+
+// printStats prints the stats of the chunker c of the named file to
+// stderr, if it keeps any.
+func printStats(name string, c any) {
+	if fc, ok := c.(*fileChunker); ok {
+		c = fc.Chunker
+	}
+	s, ok := c.(interface{ Stats() (int, int64) })
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Stats of %s: not available for this type\n", name)
+		return
+	}
+	chunks, bytes := s.Stats()
+	line := fmt.Sprintf("Stats of %s: %d chunks, %d bytes", name, chunks, bytes)
+	if mp3, ok := c.(*MP3Chunker); ok {
+		line += fmt.Sprintf(", %d frames", mp3.FramesEmitted())
+	}
+	fmt.Fprintln(os.Stderr, line)
+}
+
+// splitExtension returns the extension of the chunk files written by
+// -split: complete WAV chunks are playable .wav files, gzipped when
+// -gzip is set, other chunks are .bin files.
+func splitExtension(fileType string, gzipLevel int) string {
+	if !strings.EqualFold(fileType, "wav") {
+		return ".bin"
+	}
+	if gzipLevel != gzip.NoCompression {
+		return ".wav.gz"
+	}
+	return ".wav"
+}
+
+// stdinIsTerminal reports whether stdin is attached to a terminal rather
+// than a pipe or a file.
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
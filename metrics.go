@@ -0,0 +1,48 @@
+package main
+
+import "io"
+
+// Metrics receives counters from a chunker, e.g. to export them to
+// Prometheus or statsd. Implementations must be safe for concurrent use
+// when shared between chunkers.
+type Metrics interface {
+	IncChunks()             // a chunk was returned by Next
+	AddBytes(n int)         // n bytes were read from the input
+	IncErrors()             // Next failed with an error other than io.EOF
+	ObserveChunkSize(n int) // size of a returned chunk
+}
+
+// nopMetrics is the default Metrics that discards everything.
+type nopMetrics struct{}
+
+func (nopMetrics) IncChunks()           {}
+func (nopMetrics) AddBytes(int)         {}
+func (nopMetrics) IncErrors()           {}
+func (nopMetrics) ObserveChunkSize(int) {}
+
+// observe reports the outcome of a call to Next to m.
+func observe(m Metrics, chunk []byte, err error) ([]byte, error) {
+	switch {
+	case err == io.EOF:
+	case err != nil:
+		m.IncErrors()
+	default:
+		m.IncChunks()
+		m.ObserveChunkSize(len(chunk))
+	}
+	return chunk, err
+}
+
+// countingReader reports the number of bytes read to a Metrics.
+type countingReader struct {
+	r io.Reader
+	m Metrics
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if n > 0 {
+		r.m.AddBytes(n)
+	}
+	return n, err
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// captureMetrics records everything reported to it.
+type captureMetrics struct {
+	chunks, bytes, errors int
+	sizes                 []int
+}
+
+func (m *captureMetrics) IncChunks()             { m.chunks++ }
+func (m *captureMetrics) AddBytes(n int)         { m.bytes += n }
+func (m *captureMetrics) IncErrors()             { m.errors++ }
+func (m *captureMetrics) ObserveChunkSize(n int) { m.sizes = append(m.sizes, n) }
+
+func TestWithMetrics(t *testing.T) {
+	wav := makeWAV(2, 44100, 16, makeAudio(50001, 0x3c))
+	mp3 := makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, 50)
+
+	tests := []struct {
+		name  string
+		input []byte
+		new   func(r *bytes.Reader, m Metrics) Chunker
+	}{
+		{"wav", wav, func(r *bytes.Reader, m Metrics) Chunker { return NewWAVChunker(r, WithMetrics(m)) }},
+		{"mp3", mp3, func(r *bytes.Reader, m Metrics) Chunker { return NewMP3Chunker(r, 4096, 511, WithMetrics(m)) }},
+		{"dumb", wav, func(r *bytes.Reader, m Metrics) Chunker { return NewDumbChunker(r, 1024, WithMetrics(m)) }},
+	}
+
+	for _, tt := range tests {
+		m := &captureMetrics{}
+		chunks := readAllChunks(t, tt.new(bytes.NewReader(tt.input), m))
+
+		if m.chunks != len(chunks) {
+			t.Errorf("%s: got %d chunks, want %d", tt.name, m.chunks, len(chunks))
+		}
+		if m.bytes != len(tt.input) {
+			t.Errorf("%s: got %d bytes read, want %d", tt.name, m.bytes, len(tt.input))
+		}
+		if m.errors != 0 {
+			t.Errorf("%s: got %d errors, want 0", tt.name, m.errors)
+		}
+		for i, chunk := range chunks {
+			if i >= len(m.sizes) || m.sizes[i] != len(chunk) {
+				t.Errorf("%s: chunk %d size not observed", tt.name, i)
+				break
+			}
+		}
+	}
+
+	m := &captureMetrics{}
+	if _, err := NewWAVChunker(bytes.NewReader([]byte("not a wav file")), WithMetrics(m)).Next(); err == nil {
+		t.Fatal("Next() succeeded on invalid input")
+	}
+	if m.errors != 1 {
+		t.Errorf("got %d errors, want 1", m.errors)
+	}
+}
@@ -2,199 +2,654 @@
 package main
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
 	"io"
+	"sync/atomic"
+	"time"
 )
 
 const (
 	maxReservoir = 511  // largest possible bit-reservoir (ISO spec)
 	minFrameSize = 24   // smallest valid frame header + side-info
 	maxFrameSize = 1732 // maximum frame size for MPEG-1 Layer I at 320kbps
+
+	// mp3BufferSize is the size of the input buffer, which holds the
+	// lookahead for measuring free-format frames and any whole frame.
+	mp3BufferSize = 16 << 10
 )
 
 // ErrInvalidFrame is returned when the bit-stream does not contain a valid MP3 frame.
 var ErrInvalidFrame = errors.New("invalid or unsupported MP3 frame")
 
+// Reasons a frame header is rejected for. They wrap ErrInvalidFrame, so
+// errors.Is matches them against it. They are allocated once as they are
+// returned for every false sync while scanning for frames.
+var (
+	errShortHeader        = fmt.Errorf("%w: header is not 4 bytes", ErrInvalidFrame)
+	errNoSync             = fmt.Errorf("%w: missing frame sync", ErrInvalidFrame)
+	errReservedVersion    = fmt.Errorf("%w: reserved MPEG version", ErrInvalidFrame)
+	errReservedLayer      = fmt.Errorf("%w: reserved layer", ErrInvalidFrame)
+	errMPEG25Layer        = fmt.Errorf("%w: MPEG-2.5 layer not III", ErrInvalidFrame)
+	errBitrateIndex0      = fmt.Errorf("%w: invalid bitrate index 0", ErrInvalidFrame)
+	errBitrateIndex15     = fmt.Errorf("%w: invalid bitrate index 15", ErrInvalidFrame)
+	errReservedSampleRate = fmt.Errorf("%w: reserved sample rate", ErrInvalidFrame)
+	errReservedEmphasis   = fmt.Errorf("%w: reserved emphasis", ErrInvalidFrame)
+)
+
+// ErrFreeFormatUnsupported is returned when the size of free-format frames
+// cannot be measured because no second frame follows the first one closely.
+var ErrFreeFormatUnsupported = errors.New("free-format MP3 frame size cannot be determined")
+
+// ErrUnrecoverable is returned by ClearError when the chunker cannot resume.
+var ErrUnrecoverable = errors.New("chunker error is not recoverable")
+
 // MP3Chunker yields MP3 chunks suitable for HTTP streaming.
-// Each chunk starts with a valid frame boundary and includes previous data for bit reservoir.
+// Each chunk starts with the tail of the previous chunk referenced by its
+// first frame through the bit reservoir, followed by whole frames. The
+// reservoir bytes are decoding context only: ReservoirLen reports how
+// many leading bytes to discard when joining chunks for playback.
 type MP3Chunker struct {
-	r            io.Reader
-	targetSize   int
-	buf          []byte
-	err          error
-	reservoir    []byte // bit reservoir data from previous chunks
-	reservoirCap int
+	canceler
+	chunkStats
+	r               *bufio.Reader
+	src             io.Reader                 // input buffered by r
+	wrap            func(io.Reader) io.Reader // applies the reader options
+	targetSize      int
+	buf             []byte
+	err             error
+	reservoir       []byte // bit reservoir data from previous chunks
+	reservoirCap    int
+	actualReservoir int         // reservoir bytes at the start of the last chunk
+	spans           []frameSpan // frames of the last chunk
+	framesPerChunk  int         // when non-zero, overrides targetSize
+	lenientEmphasis bool
+	conceal         bool // replace corrupt frames with silence
+	frames          int  // number of frames read so far
+	xing            *XingHeader
+	started         bool   // the leading tag was read
+	tagSent         bool   // the leading tag was prepended to the first chunk
+	peekedFirst     bool   // the first frame was read ahead by StreamInfo
+	id3             []byte // leading ID3v2 tag
+	id3v1           []byte // trailing ID3v1 tag
+	unread          []byte // frame header handed back by StreamInfo
+	prependTags     bool
+	trimSilence     bool
+	trimFrames      int      // trailing padding frames to drop
+	pending         [][]byte // frames held back until more frames follow
+	freeFormatSize  int      // unpadded length of free-format frames, once measured
+	last            [4]byte  // header of the last frame found by scanning
+	lastSize        [2]int   // frame lengths in the format of last by padding bit
+	hasLast         bool
+	segmentSilence  time.Duration // when non-zero, cut chunks at silences this long
+	segmentGain     int           // largest global gain of a silent frame
+	silentRun       time.Duration // duration of the current run of silent frames
+	framesEmitted   atomic.Int64  // frames of the chunks returned by Next
+	metrics         Metrics
 }
 
 // NewMP3Chunker returns a new MP3Chunker that reads from r.
-func NewMP3Chunker(r io.Reader, chunkSize, reservoirSize int) *MP3Chunker {
+func NewMP3Chunker(r io.Reader, chunkSize, reservoirSize int, opts ...Option) *MP3Chunker {
 	if reservoirSize > maxReservoir {
 		reservoirSize = maxReservoir
 	}
-	return &MP3Chunker{
-		r:            r,
-		targetSize:   chunkSize,
-		buf:          make([]byte, 4),
-		reservoirCap: reservoirSize,
+	o := newOptions(opts)
+	r = o.reader(r)
+	c := &MP3Chunker{
+		r:               bufio.NewReaderSize(r, mp3BufferSize),
+		src:             r,
+		wrap:            o.reader,
+		targetSize:      chunkSize,
+		buf:             make([]byte, 4),
+		reservoirCap:    reservoirSize,
+		framesPerChunk:  o.framesPerChunk,
+		lenientEmphasis: o.lenientEmphasis,
+		conceal:         o.conceal,
+		prependTags:     o.prependTags,
+		trimSilence:     o.trimSilence,
+		segmentSilence:  o.segmentSilence,
+		segmentGain:     o.segmentGain,
+		metrics:         o.metrics,
+		chunkStats:      chunkStats{maxChunks: o.maxChunks},
+	}
+	c.err = c.checkChunkSize()
+	return c
+}
+
+// checkChunkSize reports an invalid chunk size, unless the chunks are
+// sized by WithFramesPerChunk.
+func (c *MP3Chunker) checkChunkSize() error {
+	if c.framesPerChunk > 0 {
+		return nil
 	}
+	return validateChunkSize(c.targetSize)
 }
 
 // frameLength returns the length in bytes of the frame described by hdr.
 // hdr must be exactly four bytes.
 func frameLength(hdr []byte) (int, error) {
 	if len(hdr) != 4 {
-		return 0, ErrInvalidFrame
+		return 0, errShortHeader
 	}
 
 	// Check frame sync - must be 0xFF followed by at least 0xE0
 	if hdr[0] != 0xff || hdr[1]&0xe0 != 0xe0 {
-		return 0, ErrInvalidFrame
+		return 0, errNoSync
 	}
 
-	// MPEG version and layer
+	// MPEG version and layer, validated when looking up the frame parameters
 	mpegVer := (hdr[1] >> 3) & 0x03
 	layer := (hdr[1] >> 1) & 0x03
-	if mpegVer == 1 || layer != 1 { // mpegVer == 1 is reserved, layer != 1 means not Layer III
-		return 0, ErrInvalidFrame
-	}
 
 	// Check other reserved/invalid values
 	bitRateIdx := (hdr[2] >> 4) & 0x0f
-	if bitRateIdx == 0 || bitRateIdx == 0x0f {
-		return 0, ErrInvalidFrame
-	}
 	sampleRateIdx := (hdr[2] >> 2) & 0x03
-	if sampleRateIdx == 3 {
-		return 0, ErrInvalidFrame
-	}
 	emphasis := hdr[3] & 0x03
 	if emphasis == 2 {
-		return 0, ErrInvalidFrame
+		return 0, errReservedEmphasis
 	}
 
-	// Determine bitrate and sample rate tables based on MPEG version
-	var bitrates []int
-	var sampleRates []int
-	var multiplier int
-
-	if mpegVer == 3 { // MPEG-1
-		bitrates = []int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
-		sampleRates = []int{44100, 48000, 32000, 0}
-		multiplier = 144
-	} else { // MPEG-2 and MPEG-2.5
-		bitrates = []int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
-		sampleRates = []int{22050, 24000, 16000, 0}
-		if mpegVer == 0 { // MPEG-2.5
-			sampleRates = []int{11025, 12000, 8000, 0}
-		}
-		multiplier = 72
+	params, err := lookupFrameParams(mpegVer, layer, bitRateIdx, sampleRateIdx)
+	if err != nil {
+		return 0, err
 	}
 
 	padding := int((hdr[2] >> 1) & 1)
-	bitRate := bitrates[bitRateIdx] * 1000
-	sampleRate := sampleRates[sampleRateIdx]
+	return params.size(padding), nil
+}
+
+// isLayerIII reports whether the frame header in hdr is of Layer III, the
+// only layer with side information and a bit reservoir.
+func isLayerIII(hdr []byte) bool {
+	return (hdr[1]>>1)&0x03 == layerIII
+}
 
-	if bitRate == 0 || sampleRate == 0 {
-		return 0, ErrInvalidFrame
+// frameParamsOf returns the frame parameters of the frame header in hdr.
+func frameParamsOf(hdr []byte) (frameParams, error) {
+	if len(hdr) < 4 {
+		return frameParams{}, errShortHeader
 	}
+	return lookupFrameParams((hdr[1]>>3)&0x03, (hdr[1]>>1)&0x03, (hdr[2]>>4)&0x0f, (hdr[2]>>2)&0x03)
+}
+
+// MPEG audio versions as encoded in the frame header
+const (
+	mpeg25 = 0
+	mpeg2  = 2
+	mpeg1  = 3
+)
+
+// MPEG audio layers as encoded in the frame header
+const (
+	layerIII = 1
+	layerII  = 2
+	layerI   = 3
+)
+
+// Bitrate tables in kbps indexed by the bitrate index
+var (
+	bitratesV1L1 = [16]int{0, 32, 64, 96, 128, 160, 192, 224, 256, 288, 320, 352, 384, 416, 448, 0}
+	bitratesV1L2 = [16]int{0, 32, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 384, 0}
+	bitratesV1L3 = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	bitratesV2L1 = [16]int{0, 32, 48, 56, 64, 80, 96, 112, 128, 144, 160, 176, 192, 224, 256, 0}
+	bitratesV2L3 = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0} // also Layer II
+)
+
+// Sample rate tables in Hz indexed by the sample rate index
+var (
+	sampleRatesV1  = [4]int{44100, 48000, 32000, 0}
+	sampleRatesV2  = [4]int{22050, 24000, 16000, 0}
+	sampleRatesV25 = [4]int{11025, 12000, 8000, 0}
+)
+
+// frameParams describes the frames of a single MPEG version and layer.
+type frameParams struct {
+	bitRate         int // bits per second
+	sampleRate      int // Hz
+	samplesPerFrame int
+	slotSize        int // bytes per slot, the unit of padding
+}
 
-	return multiplier*bitRate/sampleRate + padding, nil
+// size returns the frame length in bytes for the given padding bit.
+func (p frameParams) size(padding int) int {
+	return (p.samplesPerFrame/8/p.slotSize*p.bitRate/p.sampleRate + padding) * p.slotSize
+}
+
+// lookupFrameParams selects the frame parameters for the given header fields.
+// The reserved version and layer, MPEG-2.5 outside Layer III and reserved
+// or free-format indices yield errors wrapping ErrInvalidFrame.
+func lookupFrameParams(mpegVer, layer, bitRateIdx, sampleRateIdx byte) (frameParams, error) {
+	switch {
+	case bitRateIdx == 0:
+		return frameParams{}, errBitrateIndex0
+	case bitRateIdx == 15:
+		return frameParams{}, errBitrateIndex15
+	case bitRateIdx > 15:
+		return frameParams{}, ErrInvalidFrame
+	case sampleRateIdx >= 3:
+		return frameParams{}, errReservedSampleRate
+	}
+
+	var sampleRates *[4]int
+	switch mpegVer {
+	case mpeg1:
+		sampleRates = &sampleRatesV1
+	case mpeg2:
+		sampleRates = &sampleRatesV2
+	case mpeg25:
+		sampleRates = &sampleRatesV25
+	default:
+		return frameParams{}, errReservedVersion
+	}
+
+	// MPEG-2.5 is an extension defined for Layer III only.
+	if mpegVer == mpeg25 && layer != layerIII {
+		return frameParams{}, errMPEG25Layer
+	}
+
+	// Layer I frames are counted in 4-byte slots, so the padding bit adds
+	// four bytes and the length is rounded down to a multiple of four.
+	var p frameParams
+	switch {
+	case mpegVer == mpeg1 && layer == layerI:
+		p = frameParams{bitRate: bitratesV1L1[bitRateIdx], samplesPerFrame: 384, slotSize: 4}
+	case layer == layerI:
+		p = frameParams{bitRate: bitratesV2L1[bitRateIdx], samplesPerFrame: 384, slotSize: 4}
+	case mpegVer == mpeg1 && layer == layerII:
+		p = frameParams{bitRate: bitratesV1L2[bitRateIdx], samplesPerFrame: 1152, slotSize: 1}
+	case layer == layerII:
+		p = frameParams{bitRate: bitratesV2L3[bitRateIdx], samplesPerFrame: 1152, slotSize: 1}
+	case mpegVer == mpeg1 && layer == layerIII:
+		p = frameParams{bitRate: bitratesV1L3[bitRateIdx], samplesPerFrame: 1152, slotSize: 1}
+	case layer == layerIII:
+		p = frameParams{bitRate: bitratesV2L3[bitRateIdx], samplesPerFrame: 576, slotSize: 1}
+	default:
+		return frameParams{}, errReservedLayer
+	}
+
+	p.bitRate *= 1000
+	p.sampleRate = sampleRates[sampleRateIdx]
+	if p.bitRate == 0 || p.sampleRate == 0 {
+		return frameParams{}, ErrInvalidFrame
+	}
+	return p, nil
+}
+
+// frameLength returns the length in bytes of the frame described by hdr,
+// accepting the reserved emphasis value when the chunker is lenient.
+func (c *MP3Chunker) frameLength(hdr []byte) (int, error) {
+	if c.lenientEmphasis && len(hdr) == 4 && hdr[3]&0x03 == 2 {
+		hdr = []byte{hdr[0], hdr[1], hdr[2], hdr[3] &^ 0x03}
+	}
+	if len(hdr) == 4 && hdr[2]>>4 == 0 {
+		return c.freeFormatLength(hdr)
+	}
+	return frameLength(hdr)
+}
+
+// freeFormatLength returns the length of the free-format frame described by
+// hdr. Until the frame size of the stream is measured it fails with
+// errBitrateIndex0, and only if the other header fields are valid.
+func (c *MP3Chunker) freeFormatLength(hdr []byte) (int, error) {
+	// Validate the other fields with the lowest bitrate index instead
+	probe := []byte{hdr[0], hdr[1], hdr[2] | 0x10, hdr[3]}
+	if _, err := frameLength(probe); err != nil {
+		return 0, err
+	}
+	if c.freeFormatSize == 0 {
+		return 0, errBitrateIndex0
+	}
+	return c.freeFormatSize + freeFormatPadding(hdr), nil
+}
+
+// freeFormatPadding returns the number of padding bytes of the frame
+// described by hdr: one slot if the padding bit is set.
+func freeFormatPadding(hdr []byte) int {
+	if (hdr[2]>>1)&1 == 0 {
+		return 0
+	}
+	if (hdr[1]>>1)&0x03 == layerI {
+		return 4
+	}
+	return 1
+}
+
+// measureFreeFormat measures the frame size of a free-format stream as the
+// distance from the frame header in hdr, which was just read, to the next
+// header of the same format within 4*maxFrameSize bytes. The bytes are
+// only peeked at, they stay unread.
+func (c *MP3Chunker) measureFreeFormat(hdr []byte) error {
+	ahead, err := c.r.Peek(4 * maxFrameSize)
+	if isErrNotEOF(err) {
+		return err
+	}
+
+	for i := minFrameSize - 4; i+3 <= len(ahead); i++ {
+		if ahead[i] == 0xff && ahead[i+1] == hdr[1] && ahead[i+2]&0xfc == hdr[2]&0xfc {
+			c.freeFormatSize = 4 + i - freeFormatPadding(hdr)
+			return nil
+		}
+	}
+	return ErrFreeFormatUnsupported
 }
 
 // findNextFrame finds the next valid MP3 frame header in the stream
 func (c *MP3Chunker) findNextFrame() ([]byte, error) {
+	if c.unread != nil {
+		hdr := c.unread
+		c.unread = nil
+		return hdr, nil
+	}
+
 	for {
-		// Read one byte at a time looking for sync
-		n, err := io.ReadFull(c.r, c.buf[:1])
+		// Scan the buffered input one byte at a time looking for sync
+		b, err := c.r.ReadByte()
 		if err != nil {
 			return nil, err
 		}
-		if n != 1 {
-			return nil, io.ErrUnexpectedEOF
+
+		// Stop at a trailing ID3v1 tag, which may contain false syncs
+		if b == 'T' {
+			ok, err := c.readID3v1()
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				return nil, io.EOF
+			}
+			continue
 		}
 
 		// Check for sync byte
-		if c.buf[0] != 0xff {
+		if b != 0xff {
 			continue
 		}
 
-		// Read the next 3 bytes to complete potential header
-		n, err = io.ReadFull(c.r, c.buf[1:4])
-		if err != nil {
+		// Peek at the next 3 bytes to complete the potential header. They
+		// stay unread on a false sync, as the next sync may start among them.
+		next, err := c.r.Peek(3)
+		if len(next) < 3 {
+			if err == io.EOF && len(next) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
 			return nil, err
 		}
-		if n != 3 {
-			return nil, io.ErrUnexpectedEOF
-		}
+		c.buf[0] = b
+		copy(c.buf[1:4], next)
 
 		// Check if this is a valid frame header
-		if c.buf[1]&0xe0 == 0xe0 {
-			if _, err := frameLength(c.buf[:4]); err == nil {
-				return append([]byte(nil), c.buf[:4]...), nil
+		if c.buf[1]&0xe0 != 0xe0 {
+			continue
+		}
+		_, err = c.frameLength(c.buf[:4])
+		if err != nil && err != errBitrateIndex0 {
+			continue
+		}
+		c.r.Discard(3)
+		if err == errBitrateIndex0 {
+			// First free-format frame, measure the frame size once
+			if err := c.measureFreeFormat(c.buf[:4]); err != nil {
+				return nil, err
 			}
 		}
+		return append([]byte(nil), c.buf[:4]...), nil
+	}
+}
+
+// readFrameHeader returns the header and the length of the next frame.
+// In a constant-bitrate stream every frame has the format of the previous
+// one, so a header that does is taken without validating it again; the
+// input is scanned for sync only when the header does not line up. The
+// header is valid until the next call.
+func (c *MP3Chunker) readFrameHeader() ([]byte, int, error) {
+	if c.hasLast && c.unread == nil {
+		if hdr, err := c.r.Peek(4); err == nil && sameFormat(hdr, c.last[:]) {
+			copy(c.buf, hdr)
+			c.r.Discard(4)
+			return c.buf[:4], c.lastSize[(c.buf[2]>>1)&1], nil
+		}
+	}
+
+	hdr, err := c.findNextFrame()
+	if err != nil {
+		return nil, 0, err
+	}
+	frameLen, err := c.frameLength(hdr)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !c.hasLast || !sameFormat(hdr, c.last[:]) {
+		c.setLast(hdr)
+	}
+	return hdr, frameLen, nil
+}
 
-		// False sync - shift buffer and try again
-		c.buf[0] = c.buf[1]
-		c.buf[1] = c.buf[2]
-		c.buf[2] = c.buf[3]
+// setLast records the format of the frame header in hdr for the
+// constant-bitrate fast path of readFrameHeader.
+func (c *MP3Chunker) setLast(hdr []byte) {
+	c.hasLast = false
+	h := [4]byte{hdr[0], hdr[1], hdr[2] &^ 0x02, hdr[3]}
+	unpadded, err := c.frameLength(h[:])
+	if err != nil {
+		return
 	}
+	h[2] |= 0x02
+	padded, err := c.frameLength(h[:])
+	if err != nil {
+		return
+	}
+	c.last, c.lastSize, c.hasLast = h, [2]int{unpadded, padded}, true
+}
+
+// sameFormat reports whether the frame headers a and b agree in all fields
+// that affect the frame length or validity, except for the padding bit.
+func sameFormat(a, b []byte) bool {
+	return a[0] == b[0] && a[1] == b[1] && a[2]&0xfc == b[2]&0xfc && a[3]&0x03 == b[3]&0x03
 }
 
 // Next returns the next chunk or io.EOF when done.
 func (c *MP3Chunker) Next() ([]byte, error) {
+	if c.exhausted() {
+		return nil, io.EOF
+	}
+	chunk, err := c.guard(c.next, c.cancelCleanup)
+	c.count(chunk, err)
+	if err == nil {
+		c.framesEmitted.Add(int64(len(c.spans)))
+	}
+	return observe(c.metrics, chunk, err)
+}
+
+// FramesEmitted returns the number of frames in the chunks returned by
+// Next so far, not counting the bit reservoir carried over.
+func (c *MP3Chunker) FramesEmitted() int {
+	return int(c.framesEmitted.Load())
+}
+
+// next reads frames until the chunk is complete.
+func (c *MP3Chunker) next() ([]byte, error) {
 	if c.err != nil {
 		return nil, c.err
 	}
 
-	// Start chunk with reservoir data
-	chunk := append([]byte(nil), c.reservoir...)
-	remaining := c.targetSize - len(chunk)
+	if err := c.start(); err != nil {
+		c.err = err
+		return nil, err
+	}
+
+	// Start the first chunk with the leading tag, if requested
+	var chunk []byte
+	if c.prependTags && !c.tagSent {
+		chunk = append(chunk, c.id3...)
+		c.tagSent = true
+	}
+	tagLen := len(chunk)
+
+	// The reservoir is carried once the first frame tells how much of it
+	// is referenced
+	start := len(chunk)
+	remaining := c.targetSize
+	c.actualReservoir = 0
+	prev := c.spans
+	c.spans = nil
 
 	// Read frames until we have enough data
-	for remaining > 0 {
+	for frames := 0; !c.full(remaining, frames); frames++ {
 		// Find next frame header
-		hdr, err := c.findNextFrame()
+		hdr, frameLen, err := c.readFrameHeader()
 		if err != nil {
 			c.err = err
-			if len(chunk) > len(c.reservoir) {
-				return c.finalize(chunk), nil
+			if len(chunk) > start {
+				return c.finalize(chunk, tagLen), nil
 			}
 			return nil, err
 		}
 
-		// Get frame length
-		frameLen, err := frameLength(hdr)
-		if err != nil {
-			c.err = err
-			return nil, err
-		}
-
 		// Read the rest of the frame
 		frame := make([]byte, frameLen)
 		copy(frame, hdr)
 		if _, err := io.ReadFull(c.r, frame[4:]); err != nil {
 			c.err = err
-			if len(chunk) > len(c.reservoir) {
-				return c.finalize(chunk), nil
+			if c.conceal {
+				// Keep the timeline intact by replacing the truncated frame
+				chunk = append(chunk, silentFrame(hdr, frameLen)...)
+				c.spans = append(c.spans, frameSpan{size: frameLen, overhead: frameOverhead(hdr)})
+			}
+			if len(chunk) > start {
+				return c.finalize(chunk, tagLen), nil
 			}
 			return nil, err
 		}
 
+		if c.frames == 0 {
+			c.parseXing(frame)
+		}
+		c.frames++
+
+		if c.conceal && !checkCRC(frame) {
+			frame = silentFrame(hdr, frameLen)
+		}
+
+		if c.trimFrames > 0 {
+			// Hold back the trailing padding frames, they are dropped at EOF
+			c.pending = append(c.pending, frame)
+			if len(c.pending) <= c.trimFrames {
+				frames--
+				continue
+			}
+			frame, c.pending = c.pending[0], c.pending[1:]
+		}
+
+		if frames == 0 && len(c.reservoir) > 0 {
+			chunk = c.carryReservoir(chunk, frame, prev)
+			remaining -= c.actualReservoir
+			start = len(chunk)
+		}
+
 		// Add frame to chunk
 		chunk = append(chunk, frame...)
+		c.spans = append(c.spans, frameSpan{size: len(frame), overhead: frameOverhead(frame)})
 		remaining -= len(frame)
+
+		if c.segmentSilence > 0 && c.silenceBreak(frame) {
+			break
+		}
 	}
 
-	return c.finalize(chunk), nil
+	return c.finalize(chunk, tagLen), nil
 }
 
-// finalize trims the reservoir for the next iteration.
-func (c *MP3Chunker) finalize(chunk []byte) []byte {
-	if len(chunk) > c.reservoirCap {
-		c.reservoir = append([]byte(nil), chunk[len(chunk)-c.reservoirCap:]...)
+// start reads the leading ID3v2 tag once, before the first frame.
+func (c *MP3Chunker) start() error {
+	if c.started {
+		return nil
+	}
+	c.started = true
+	return c.readID3v2()
+}
+
+// ClearError clears the error of the last call to Next so that chunking
+// resumes, e.g. after a transient read error once the caller reconnected
+// the input. The caller must have repositioned the input at a frame
+// boundary; the frame being read when the error occurred is lost, bytes
+// read ahead by the chunker are discarded. ClearError fails with
+// ErrUnrecoverable once the stream ended or the chunker was canceled.
+func (c *MP3Chunker) ClearError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch {
+	case c.err == nil:
+		return nil
+	case c.err == io.EOF, c.err == ErrCanceled, c.err == ErrFreeFormatUnsupported, c.err == ErrInvalidID3Tag,
+		errors.Is(c.err, ErrInvalidFrame), errors.Is(c.err, ErrInvalidChunkSize):
+		return ErrUnrecoverable
+	}
+	c.err = nil
+	c.r.Reset(c.src)
+	c.unread = nil
+	return nil
+}
+
+// Reset makes the chunker start over on a new stream read from r, keeping
+// its settings and reusing its buffers. The reader options apply to r as
+// they did to the original reader. Reset may be called at any time, also
+// after Next failed or the chunker was canceled.
+func (c *MP3Chunker) Reset(r io.Reader) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.src = c.wrap(r)
+	c.r.Reset(c.src)
+	c.unread = nil
+	c.err = c.checkChunkSize()
+	c.reservoir = c.reservoir[:0]
+	c.actualReservoir = 0
+	c.spans = nil
+	c.frames = 0
+	c.xing = nil
+	c.started, c.tagSent, c.peekedFirst = false, false, false
+	c.id3, c.id3v1 = nil, nil
+	c.trimFrames = 0
+	c.pending = nil
+	c.freeFormatSize = 0
+	c.hasLast = false
+	c.silentRun = 0
+	c.resetStats()
+	c.framesEmitted.Store(0)
+	c.canceled.Store(false)
+}
+
+// full reports whether a chunk with remaining bytes left to the target
+// size and the given number of frames is complete.
+func (c *MP3Chunker) full(remaining, frames int) bool {
+	if c.framesPerChunk > 0 {
+		return frames >= c.framesPerChunk
+	}
+	return remaining <= 0
+}
+
+// finalize keeps the tail of chunk, up to the reservoir size, as the
+// candidate reservoir of the next chunk, of which only the part referenced
+// by its first frame is carried. The first skip bytes of chunk hold the
+// prepended tag and never enter the reservoir.
+func (c *MP3Chunker) finalize(chunk []byte, skip int) []byte {
+	frames := chunk[skip:]
+	if len(frames) > c.reservoirCap {
+		c.reservoir = append(c.reservoir[:0], frames[len(frames)-c.reservoirCap:]...)
 	} else {
-		c.reservoir = append([]byte(nil), chunk...)
+		c.reservoir = append(c.reservoir[:0], frames...)
 	}
 	return chunk
 }
+
+// Close implements Chunker. An MP3Chunker holds no pooled resources, so it
+// is a no-op; closing the input is up to the caller.
+func (c *MP3Chunker) Close() error {
+	return nil
+}
+
+// IndependentChunks reports whether every chunk can be decoded in isolation,
+// which requires the bit reservoir to be carried over between chunks.
+func (c *MP3Chunker) IndependentChunks() bool {
+	return c.reservoirCap > 0
+}
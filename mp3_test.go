@@ -0,0 +1,782 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countFrames returns the number of frames in chunk, which must consist
+// of whole frames only.
+func countFrames(t *testing.T, chunk []byte) int {
+	t.Helper()
+	frames := 0
+	for len(chunk) > 0 {
+		n, err := frameLength(chunk[:4])
+		if err != nil {
+			t.Fatalf("frame %d: %v", frames, err)
+		}
+		chunk = chunk[n:]
+		frames++
+	}
+	return frames
+}
+
+func TestMP3ChunkerFramesPerChunk(t *testing.T) {
+	const framesPerChunk = 7
+
+	file, err := os.Open("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	chunks := readAllChunks(t, NewMP3Chunker(file, 8192, 0, WithFramesPerChunk(framesPerChunk)))
+
+	total := 0
+	for i, chunk := range chunks {
+		n := countFrames(t, chunk)
+		if i < len(chunks)-1 && n != framesPerChunk {
+			t.Errorf("chunk %d: got %d frames, want %d", i, n, framesPerChunk)
+		}
+		total += n
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	want := 0
+	for _, chunk := range readAllChunks(t, NewMP3Chunker(file, 8192, 0)) {
+		want += countFrames(t, chunk)
+	}
+	if total != want {
+		t.Fatalf("got %d frames in total, want %d", total, want)
+	}
+}
+
+// makeFrames returns n frames with the given header and a zeroed body.
+func makeFrames(t *testing.T, hdr []byte, n int) []byte {
+	t.Helper()
+	valid := []byte{hdr[0], hdr[1], hdr[2], hdr[3] &^ 0x03}
+	size, err := frameLength(valid)
+	if err != nil {
+		t.Fatalf("frameLength(%x) error: %v", valid, err)
+	}
+	var frames []byte
+	for i := 0; i < n; i++ {
+		frame := make([]byte, size)
+		copy(frame, hdr)
+		frames = append(frames, frame...)
+	}
+	return frames
+}
+
+func TestMP3ChunkerLenientEmphasis(t *testing.T) {
+	// MPEG-1 Layer III, 128 kbps, 44.1 kHz, reserved emphasis
+	frames := makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x02}, 5)
+
+	strict := readAllChunks(t, NewMP3Chunker(bytes.NewReader(frames), 8192, 0))
+	if len(strict) != 0 {
+		t.Errorf("strict: got %d chunks, want none", len(strict))
+	}
+
+	lenient := readAllChunks(t, NewMP3Chunker(bytes.NewReader(frames), 8192, 0, WithLenientEmphasis()))
+	if len(lenient) != 1 || !bytes.Equal(lenient[0], frames) {
+		t.Errorf("lenient: got %d chunks, want all frames in a single chunk", len(lenient))
+	}
+}
+
+func TestMP3ChunkerErrorConcealment(t *testing.T) {
+	// MPEG-1 Layer III, CRC protected, 128 kbps, 44.1 kHz, stereo
+	hdr := []byte{0xff, 0xfa, 0x90, 0x00}
+	size, err := frameLength(hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var frames [][]byte
+	var input []byte
+	for i := 0; i < 6; i++ {
+		frame := makeAudio(size, byte(i))
+		copy(frame, hdr)
+		crc := frameCRC(frame)
+		frame[4], frame[5] = byte(crc>>8), byte(crc)
+		frames = append(frames, frame)
+		input = append(input, frame...)
+	}
+	// Corrupt the side information of the third frame
+	input[2*size+10] ^= 0xff
+
+	chunks := readAllChunks(t, NewMP3Chunker(bytes.NewReader(input), 1<<20, 0, WithErrorConcealment()))
+	if len(chunks) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(chunks))
+	}
+	if n := countFrames(t, chunks[0]); n != len(frames) {
+		t.Fatalf("got %d frames, want %d", n, len(frames))
+	}
+	for i, want := range frames {
+		got := chunks[0][i*size : (i+1)*size]
+		if i == 2 {
+			want = silentFrame(hdr, size)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("frame %d mismatch", i)
+		}
+	}
+
+	// Without concealment the corrupt frame is passed through
+	chunks = readAllChunks(t, NewMP3Chunker(bytes.NewReader(input), 1<<20, 0))
+	if len(chunks) != 1 || !bytes.Equal(chunks[0], input) {
+		t.Errorf("corrupt frame not passed through without concealment")
+	}
+}
+
+// makeID3v2 returns an ID3v2.4 tag whose body contains a false frame sync.
+func makeID3v2(body []byte) []byte {
+	n := len(body)
+	tag := []byte{'I', 'D', '3', 4, 0, 0, byte(n >> 21 & 0x7f), byte(n >> 14 & 0x7f), byte(n >> 7 & 0x7f), byte(n & 0x7f)}
+	return append(tag, body...)
+}
+
+func TestMP3ChunkerPrependTags(t *testing.T) {
+	// MPEG-1 Layer III, 128 kbps, 44.1 kHz
+	frames := makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, 20)
+	tag := makeID3v2(append([]byte("TIT2\x00\x00\x00\x05\x00\x00\x03song"), 0xff, 0xfb, 0x90, 0x00))
+	input := append(append([]byte(nil), tag...), frames...)
+
+	chunker := NewMP3Chunker(bytes.NewReader(input), 2048, 512, WithPrependTags())
+	chunks := readAllChunks(t, chunker)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want at least 2", len(chunks))
+	}
+	if !bytes.Equal(chunker.ID3v2(), tag) {
+		t.Errorf("ID3v2() = %x, want %x", chunker.ID3v2(), tag)
+	}
+	if !bytes.HasPrefix(chunks[0], tag) {
+		t.Fatalf("first chunk does not start with the ID3 tag")
+	}
+	// The rest of the first chunk must be whole frames from the stream start
+	first := chunks[0][len(tag):]
+	countFrames(t, first)
+	if !bytes.HasPrefix(frames, first) {
+		t.Errorf("first chunk frames do not match the stream")
+	}
+	for i, chunk := range chunks[1:] {
+		if bytes.Contains(chunk, tag) {
+			t.Errorf("chunk %d: tag duplicated", i+1)
+		}
+	}
+
+	// Without the option the tag is parsed but not emitted
+	chunks = readAllChunks(t, NewMP3Chunker(bytes.NewReader(input), 2048, 512))
+	if len(chunks) == 0 || !bytes.HasPrefix(frames, chunks[0]) {
+		t.Errorf("tag emitted without WithPrependTags")
+	}
+
+	// A tag running past the end of the stream is reported
+	_, err := NewMP3Chunker(bytes.NewReader(tag[:len(tag)-3]), 2048, 0).Next()
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated tag: got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestMP3ChunkerActualReservoir(t *testing.T) {
+	const framesPerChunk, reservoir = 3, maxReservoir
+
+	// MPEG-1 Layer III, 128 kbps, 44.1 kHz, stereo: 417-byte frames with
+	// 36 bytes of header and side information
+	hdr := []byte{0xff, 0xfb, 0x90, 0x00}
+	frames := makeFrames(t, hdr, 30)
+	size, _ := frameLength(hdr)
+	main := size - frameOverhead(hdr)
+	mdb := func(i int) int { return i * 97 % 500 }
+	for i := 0; i < 30; i++ {
+		v := mdb(i)
+		frames[i*size+4], frames[i*size+5] = byte(v>>1), byte(v<<7)
+	}
+
+	chunker := NewMP3Chunker(bytes.NewReader(frames), 8192, reservoir, WithFramesPerChunk(framesPerChunk))
+	var out []byte
+	for i := 0; ; i++ {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := 0
+		if m := mdb(i * framesPerChunk); i > 0 && m > 0 {
+			want = m
+			if m > main {
+				want = size + m - main
+			}
+			want = min(want, reservoir)
+		}
+		if got := chunker.ActualReservoir(); got != want {
+			t.Errorf("chunk %d: got %d reservoir bytes, want %d", i, got, want)
+		}
+		out = append(out, chunk[chunker.ActualReservoir():]...)
+	}
+	if !bytes.Equal(out, frames) {
+		t.Error("chunks without the reservoir do not reassemble to the input")
+	}
+}
+
+func TestSyncsafe(t *testing.T) {
+	tests := []struct {
+		in   []byte
+		want int
+		ok   bool
+	}{
+		{[]byte{0, 0, 2, 1}, 257, true},
+		{[]byte{0x7f, 0x7f, 0x7f, 0x7f}, 1<<28 - 1, true},
+		{[]byte{0, 0, 0x80, 0}, 0, false},
+	}
+	for _, tt := range tests {
+		if got, ok := syncsafe(tt.in); got != tt.want || ok != tt.ok {
+			t.Errorf("syncsafe(%x) = %d, %v; want %d, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMP3ChunkerID3v2(t *testing.T) {
+	frames := makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, 5)
+	withFlags := func(tag []byte, version, flags byte) []byte {
+		tag = append([]byte(nil), tag...)
+		tag[3], tag[5] = version, flags
+		return tag
+	}
+
+	footer := append(makeID3v2([]byte("frames")), "3DI\x04\x00\x10\x00\x00\x00\x06"...)
+	tests := []struct {
+		name string
+		tag  []byte
+		err  error
+	}{
+		{"v2.4 extended header", withFlags(makeID3v2([]byte("\x00\x00\x00\x06\x01\x00\xff\xfb")), 4, id3v2FlagExtHeader), nil},
+		{"v2.3 extended header", withFlags(makeID3v2([]byte("\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00")), 3, id3v2FlagExtHeader), nil},
+		{"footer", withFlags(footer, 4, id3v2FlagFooter), nil},
+		{"extended header past tag", withFlags(makeID3v2([]byte("\x00\x00\x01\x00\x01\x00")), 4, id3v2FlagExtHeader), ErrInvalidID3Tag},
+		{"size past EOF", makeID3v2(make([]byte, 1<<20))[:100], io.ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		input := append(append([]byte(nil), tt.tag...), frames...)
+		chunker := NewMP3Chunker(bytes.NewReader(input), 8192, 0)
+		chunk, err := chunker.Next()
+		if err != tt.err {
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.err)
+			continue
+		}
+		if tt.err != nil {
+			continue
+		}
+		if !bytes.Equal(chunk, frames) {
+			t.Errorf("%s: tag not skipped cleanly", tt.name)
+		}
+		if !bytes.Equal(chunker.ID3v2(), tt.tag) {
+			t.Errorf("%s: ID3v2() = %q, want %q", tt.name, chunker.ID3v2(), tt.tag)
+		}
+	}
+
+	// A short input declaring the largest size is not buffered up front
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	if _, err := NewMP3Chunker(bytes.NewReader([]byte("ID3\x04\x00\x00\x7f\x7f\x7f\x7f")), 8192, 0).Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("largest size: got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+	runtime.ReadMemStats(&after)
+	if n := after.TotalAlloc - before.TotalAlloc; n > 16<<20 {
+		t.Errorf("largest size: allocated %d bytes for a short input", n)
+	}
+
+	// Bytes resembling a tag with an invalid size are scanned past
+	input := append([]byte("ID3\x04\x00\x00\x80\x80\x80\x80"), frames...)
+	chunker := NewMP3Chunker(bytes.NewReader(input), 8192, 0)
+	if chunk, err := chunker.Next(); err != nil || !bytes.Equal(chunk, frames) || chunker.ID3v2() != nil {
+		t.Errorf("invalid tag: got %d bytes, error %v", len(chunk), err)
+	}
+}
+
+func TestMP3ChunkerTrailingTag(t *testing.T) {
+	frames := makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, 5)
+	tag := make([]byte, id3v1Size)
+	copy(tag, "TAGtitle with a false sync \xff\xfb\x90\x00")
+
+	input := append(append([]byte(nil), frames...), tag...)
+	chunker := NewMP3Chunker(bytes.NewReader(input), 1<<20, 0)
+	chunks := readAllChunks(t, chunker)
+	if len(chunks) != 1 || !bytes.Equal(chunks[0], frames) {
+		t.Fatalf("got %d chunks, want the frames only", len(chunks))
+	}
+	if !bytes.Equal(chunker.TrailingTag(), tag) {
+		t.Errorf("TrailingTag() = %q, want %q", chunker.TrailingTag(), tag)
+	}
+	if err := VerifyMP3Lossless(bytes.NewReader(input), int64(len(input)), 1024); err != nil {
+		t.Errorf("VerifyMP3Lossless() error: %v", err)
+	}
+
+	// A tag followed by more data is not trailing
+	plain := make([]byte, id3v1Size)
+	copy(plain, "TAGtitle")
+	input = append(append(append([]byte(nil), frames...), plain...), frames...)
+	chunker = NewMP3Chunker(bytes.NewReader(input), 1<<20, 0)
+	chunks = readAllChunks(t, chunker)
+	if len(chunks) != 1 || countFrames(t, chunks[0]) != 10 || chunker.TrailingTag() != nil {
+		t.Errorf("mid-stream tag: got %d chunks, trailing tag %q", len(chunks), chunker.TrailingTag())
+	}
+}
+
+func TestMP3ChunkerNoDuplicateFrames(t *testing.T) {
+	input, err := os.ReadFile("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := countFrames(t, input)
+
+	for _, chunkSize := range []int{1024, 4096, 8192} {
+		chunker := NewMP3Chunker(bytes.NewReader(input), chunkSize, maxReservoir)
+		var payload []byte
+		for {
+			chunk, err := chunker.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := chunker.ReservoirLen(); n > maxReservoir {
+				t.Fatalf("chunk size %d: %d reservoir bytes, more than %d", chunkSize, n, maxReservoir)
+			}
+			payload = append(payload, chunk[chunker.ReservoirLen():]...)
+		}
+		if got := countFrames(t, payload); got != want {
+			t.Errorf("chunk size %d: got %d frames, want %d", chunkSize, got, want)
+		}
+		if !bytes.Equal(payload, input) {
+			t.Errorf("chunk size %d: payloads do not reassemble to the input", chunkSize)
+		}
+	}
+}
+
+func TestMP3ChunkerReservoirLen(t *testing.T) {
+	input, err := os.ReadFile("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, reservoir := range []int{0, 100, maxReservoir} {
+		chunker := NewMP3Chunker(bytes.NewReader(input), 2048, reservoir)
+		var prev []byte
+		for i := 0; ; i++ {
+			chunk, err := chunker.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			n := chunker.ReservoirLen()
+			if i == 0 && n != 0 || n > reservoir {
+				t.Fatalf("reservoir %d, chunk %d: ReservoirLen() = %d", reservoir, i, n)
+			}
+			// The overlap repeats the end of the previous chunk and a frame follows it
+			if !bytes.Equal(chunk[:n], prev[len(prev)-n:]) {
+				t.Fatalf("reservoir %d, chunk %d: leading %d bytes do not repeat the previous chunk", reservoir, i, n)
+			}
+			if _, err := frameLength(chunk[n : n+4]); err != nil {
+				t.Fatalf("reservoir %d, chunk %d: no frame after %d reservoir bytes: %v", reservoir, i, n, err)
+			}
+			prev = chunk
+		}
+	}
+}
+
+func TestFrameLengthAllHeaders(t *testing.T) {
+	hdr := []byte{0xff, 0, 0, 0}
+	for b1 := 0; b1 < 256; b1++ {
+		for b2 := 0; b2 < 256; b2++ {
+			for b3 := 0; b3 < 4; b3++ {
+				hdr[1], hdr[2], hdr[3] = byte(b1), byte(b2), byte(b3)
+				n, err := frameLength(hdr)
+				if err == nil && (n < minFrameSize || n > maxFrameSize) {
+					t.Fatalf("frameLength(%x) = %d, outside [%d, %d]", hdr, n, minFrameSize, maxFrameSize)
+				}
+			}
+		}
+	}
+}
+
+func TestFrameLengthLayers(t *testing.T) {
+	tests := []struct {
+		name string
+		hdr  []byte
+		want int
+	}{
+		{"MPEG-1 Layer I", []byte{0xff, 0xff, 0x14, 0x00}, 32},
+		{"MPEG-1 Layer I rounded", []byte{0xff, 0xff, 0xe0, 0x00}, 484},
+		{"MPEG-1 Layer I padded", []byte{0xff, 0xff, 0xe2, 0x00}, 488},
+		{"MPEG-1 Layer II", []byte{0xff, 0xfd, 0x80, 0x00}, 417},
+		{"MPEG-1 Layer II padded", []byte{0xff, 0xfd, 0x82, 0x00}, 418},
+		{"MPEG-1 Layer III", []byte{0xff, 0xfb, 0x90, 0x00}, 417},
+		{"MPEG-2 Layer I", []byte{0xff, 0xf7, 0xe0, 0x00}, 556},
+		{"MPEG-2 Layer I padded", []byte{0xff, 0xf7, 0xe2, 0x00}, 560},
+		{"MPEG-2 Layer II", []byte{0xff, 0xf5, 0xe4, 0x00}, 960},
+		{"MPEG-2 Layer III", []byte{0xff, 0xf3, 0xe4, 0x00}, 480},
+		{"MPEG-2.5 Layer III", []byte{0xff, 0xe3, 0x18, 0x00}, 72},
+	}
+	for _, tt := range tests {
+		got, err := frameLength(tt.hdr)
+		if err != nil {
+			t.Errorf("%s: frameLength(%x) error: %v", tt.name, tt.hdr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%s: frameLength(%x) = %d, want %d", tt.name, tt.hdr, got, tt.want)
+		}
+	}
+
+	for _, hdr := range [][]byte{
+		{0xff, 0xf9, 0x90, 0x00}, // reserved layer
+		{0xff, 0xe7, 0x18, 0x00}, // MPEG-2.5 Layer I
+		{0xff, 0xe5, 0x18, 0x00}, // MPEG-2.5 Layer II
+	} {
+		if _, err := frameLength(hdr); !errors.Is(err, ErrInvalidFrame) {
+			t.Errorf("frameLength(%x) error = %v, want ErrInvalidFrame", hdr, err)
+		}
+	}
+}
+
+func TestFrameLengthReasons(t *testing.T) {
+	tests := []struct {
+		hdr    []byte
+		reason string
+	}{
+		{[]byte{0xff, 0xfb}, "header is not 4 bytes"},
+		{[]byte{0xfe, 0xfb, 0x90, 0x00}, "missing frame sync"},
+		{[]byte{0xff, 0xeb, 0x90, 0x00}, "reserved MPEG version"},
+		{[]byte{0xff, 0xf9, 0x90, 0x00}, "reserved layer"},
+		{[]byte{0xff, 0xe5, 0x18, 0x00}, "MPEG-2.5 layer not III"},
+		{[]byte{0xff, 0xfb, 0x00, 0x00}, "invalid bitrate index 0"},
+		{[]byte{0xff, 0xfb, 0xf0, 0x00}, "invalid bitrate index 15"},
+		{[]byte{0xff, 0xfb, 0x9c, 0x00}, "reserved sample rate"},
+		{[]byte{0xff, 0xfb, 0x90, 0x02}, "reserved emphasis"},
+	}
+	for _, tt := range tests {
+		_, err := frameLength(tt.hdr)
+		if !errors.Is(err, ErrInvalidFrame) {
+			t.Errorf("frameLength(%x) error = %v, want ErrInvalidFrame", tt.hdr, err)
+			continue
+		}
+		if !strings.HasSuffix(err.Error(), ": "+tt.reason) {
+			t.Errorf("frameLength(%x) error = %q, want reason %q", tt.hdr, err, tt.reason)
+		}
+	}
+}
+
+func TestMP3ChunkerFreeFormat(t *testing.T) {
+	const size = 1000
+	var stream []byte
+	var want []int
+	for i := 0; i < 6; i++ {
+		hdr := []byte{0xff, 0xfb, 0x00, 0x00}
+		n := size
+		if i%2 == 1 {
+			hdr[2] |= 0x02 // padded
+			n++
+		}
+		frame := make([]byte, n)
+		copy(frame, hdr)
+		stream = append(stream, frame...)
+		want = append(want, n)
+	}
+
+	chunker := NewMP3Chunker(bytes.NewReader(stream), DefaultChunkSize, 0, WithFramesPerChunk(1))
+	var got []byte
+	for i := 0; ; i++ {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		if i >= len(want) || len(chunk) != want[i] {
+			t.Fatalf("chunk %d has %d bytes, want frame sizes %v", i, len(chunk), want)
+		}
+		got = append(got, chunk...)
+	}
+	if !bytes.Equal(got, stream) {
+		t.Error("chunks do not reassemble the stream")
+	}
+}
+
+func TestMP3ChunkerFreeFormatUnsupported(t *testing.T) {
+	stream := make([]byte, 5*maxFrameSize)
+	copy(stream, []byte{0xff, 0xfb, 0x00, 0x00})
+
+	chunker := NewMP3Chunker(bytes.NewReader(stream), 4096, 0)
+	if _, err := chunker.Next(); err != ErrFreeFormatUnsupported {
+		t.Fatalf("Next() error = %v, want ErrFreeFormatUnsupported", err)
+	}
+}
+
+func TestMP3ChunkerReset(t *testing.T) {
+	input, err := os.ReadFile("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := input[:len(input)/2]
+
+	chunker := NewMP3Chunker(bytes.NewReader(input), 4096, maxReservoir)
+	readAllChunks(t, chunker)
+	chunker.Reset(bytes.NewReader(second))
+	got := readAllChunks(t, chunker)
+
+	want := readAllChunks(t, NewMP3Chunker(bytes.NewReader(second), 4096, maxReservoir))
+	if len(got) != len(want) {
+		t.Fatalf("got %d chunks after Reset, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Fatalf("chunk %d after Reset differs from a fresh chunker", i)
+		}
+	}
+
+	// Reset also recovers from a hard error
+	chunker.Reset(bytes.NewReader([]byte{0xff, 0xfb, 0x00, 0x00}))
+	if _, err := chunker.Next(); err == nil || err == io.EOF {
+		t.Fatalf("Next() error = %v, want a hard error", err)
+	}
+	chunker.Reset(bytes.NewReader(second))
+	if got := readAllChunks(t, chunker); len(got) != len(want) {
+		t.Fatalf("got %d chunks after Reset from an error, want %d", len(got), len(want))
+	}
+}
+
+// readCounter counts the calls to Read of the wrapped reader.
+type readCounter struct {
+	r     io.Reader
+	reads int
+}
+
+func (r *readCounter) Read(p []byte) (int, error) {
+	r.reads++
+	return r.r.Read(p)
+}
+
+// BenchmarkMP3ChunkerReads chunks a 10 MB MP3 stream made of copies of
+// sample.mp3. The reads/op metric counts the reads issued to the input.
+func BenchmarkMP3ChunkerReads(b *testing.B) {
+	sample, err := os.ReadFile("sample.mp3")
+	if err != nil {
+		b.Fatal(err)
+	}
+	input := bytes.Repeat(sample, 10<<20/len(sample)+1)[:10<<20]
+
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+	reads := 0
+	for i := 0; i < b.N; i++ {
+		r := &readCounter{r: bytes.NewReader(input)}
+		chunker := NewMP3Chunker(r, 4096, maxReservoir)
+		for {
+			if _, err := chunker.Next(); err != nil {
+				break
+			}
+		}
+		reads += r.reads
+	}
+	b.ReportMetric(float64(reads)/float64(b.N), "reads/op")
+}
+
+func TestMP3ChunkerCBRResync(t *testing.T) {
+	hdr := []byte{0xff, 0xfb, 0x90, 0x00}
+	frames := makeFrames(t, hdr, 10)
+	other := makeFrames(t, []byte{0xff, 0xfb, 0xa0, 0x00}, 10)
+	junk := []byte{0x00, 0xff, 0x12, 0x34}
+
+	// Junk and a change of bitrate break the constant-bitrate fast path
+	var stream []byte
+	stream = append(stream, frames...)
+	stream = append(stream, junk...)
+	stream = append(stream, frames...)
+	stream = append(stream, other...)
+
+	var got []byte
+	for _, chunk := range readAllChunks(t, NewMP3Chunker(bytes.NewReader(stream), DefaultChunkSize, 0, WithFramesPerChunk(1))) {
+		got = append(got, chunk...)
+	}
+	want := append(append(append([]byte(nil), frames...), frames...), other...)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %d bytes of frames, want %d", len(got), len(want))
+	}
+}
+
+// BenchmarkMP3ChunkerCBR chunks 10 MB of 128 kbps CBR frames alternating
+// between padded and unpadded ones.
+func BenchmarkMP3ChunkerCBR(b *testing.B) {
+	hdr := []byte{0xff, 0xfb, 0x90, 0x00}
+	var input []byte
+	for i := 0; len(input) < 10<<20; i++ {
+		hdr[2] = 0x90 | byte(i%2)<<1
+		size, err := frameLength(hdr)
+		if err != nil {
+			b.Fatal(err)
+		}
+		frame := make([]byte, size)
+		copy(frame, hdr)
+		input = append(input, frame...)
+	}
+
+	b.SetBytes(int64(len(input)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chunker := NewMP3Chunker(bytes.NewReader(input), 4096, maxReservoir)
+		for {
+			if _, err := chunker.Next(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func FuzzFrameLength(f *testing.F) {
+	f.Add([]byte{0xff, 0xfb, 0x90, 0x00})
+	f.Add([]byte{0xff, 0xf3, 0x48, 0xc4})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+	f.Fuzz(func(t *testing.T, hdr []byte) {
+		n, err := frameLength(hdr)
+		if err == nil && n <= 0 {
+			t.Fatalf("frameLength(%x) = %d with no error", hdr, n)
+		}
+	})
+}
+
+// failingReader fails once with errTransient when reaching offset failAt.
+type failingReader struct {
+	*bytes.Reader
+	failAt int64
+	failed bool
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	off := r.Size() - int64(r.Len())
+	if !r.failed && off+int64(len(p)) > r.failAt {
+		if off >= r.failAt {
+			r.failed = true
+			return 0, errTransient
+		}
+		p = p[:r.failAt-off]
+	}
+	return r.Reader.Read(p)
+}
+
+func TestMP3ChunkerClearError(t *testing.T) {
+	hdr := []byte{0xff, 0xfb, 0x90, 0x00}
+	size, _ := frameLength(hdr)
+	frames := makeFrames(t, hdr, 20)
+	for i := range frames {
+		if i%size >= 4 {
+			frames[i] = byte(i / size) // tell the frames apart
+		}
+	}
+
+	r := &failingReader{Reader: bytes.NewReader(frames), failAt: int64(7*size + 100)}
+	chunker := NewMP3Chunker(r, 3*size, 0)
+
+	var got []byte
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == errTransient {
+			// Reconnect at the start of the frame being read
+			r.Seek(int64(7*size), io.SeekStart)
+			if err := chunker.ClearError(); err != nil {
+				t.Fatalf("ClearError() error: %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		got = append(got, chunk...)
+	}
+	if !r.failed {
+		t.Fatal("transient error not injected")
+	}
+	if !bytes.Equal(got, frames) {
+		t.Error("resumed chunks do not reassemble to the input")
+	}
+
+	if err := chunker.ClearError(); err != ErrUnrecoverable {
+		t.Errorf("ClearError() after EOF: got %v, want %v", err, ErrUnrecoverable)
+	}
+}
+
+// setGlobalGain sets the global gain of every granule and channel of the
+// MPEG-1 stereo Layer III frame without CRC at the start of frame.
+func setGlobalGain(frame []byte, gain int) {
+	for i := 0; i < 4; i++ {
+		off := 4*8 + 20 + i*granuleBitsV1 + gainOffset
+		for bit := 0; bit < 8; bit++ {
+			mask := byte(0x80) >> ((off + bit) % 8)
+			if gain>>(7-bit)&1 == 1 {
+				frame[(off+bit)/8] |= mask
+			} else {
+				frame[(off+bit)/8] &^= mask
+			}
+		}
+	}
+}
+
+func TestMP3ChunkerSilenceSegmentation(t *testing.T) {
+	// MPEG-1 Layer III, 128 kbps, 44.1 kHz, 26 ms per frame
+	hdr := []byte{0xff, 0xfb, 0x90, 0x00}
+	size, err := frameLength(hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	input := makeFrames(t, hdr, 20)
+	for i := 0; i < 20; i++ {
+		if i < 8 || i >= 16 {
+			setGlobalGain(input[i*size:], 180)
+		}
+	}
+	for i := 0; i < 20; i++ {
+		want := 0
+		if i < 8 || i >= 16 {
+			want = 180
+		}
+		if gain, ok := maxGlobalGain(input[i*size : (i+1)*size]); !ok || gain != want {
+			t.Fatalf("frame %d: got global gain %d, %v, want %d", i, gain, ok, want)
+		}
+	}
+
+	// The fourth silent frame completes 100 ms of silence
+	chunks := readAllChunks(t, NewMP3Chunker(bytes.NewReader(input), 1<<20, 0, WithSilenceSegmentation(100*time.Millisecond, 10)))
+	if len(chunks) != 2 {
+		t.Fatalf("got %d chunks, want 2", len(chunks))
+	}
+	if n := countFrames(t, chunks[0]); n != 12 {
+		t.Errorf("first chunk: got %d frames, want 12", n)
+	}
+	if !bytes.Equal(append(chunks[0], chunks[1]...), input) {
+		t.Errorf("chunks do not join to the input")
+	}
+
+	// A longer minimum silence is never reached
+	chunks = readAllChunks(t, NewMP3Chunker(bytes.NewReader(input), 1<<20, 0, WithSilenceSegmentation(time.Second, 10)))
+	if len(chunks) != 1 {
+		t.Errorf("got %d chunks, want 1", len(chunks))
+	}
+}
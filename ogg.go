@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// ErrInvalidOggPage is returned when a page lacks the OggS capture
+// pattern, has an unknown version or fails its CRC-32 check.
+var ErrInvalidOggPage = errors.New("invalid ogg page")
+
+const (
+	oggHeaderSize = 27 // page header up to the segment table
+	oggFlagBOS    = 0x02
+)
+
+// oggCRCTable is the lookup table of the Ogg CRC-32, which uses the
+// polynomial 0x04c11db7 without the bit reflection of hash/crc32.
+var oggCRCTable = func() (t [256]uint32) {
+	for i := range t {
+		crc := uint32(i) << 24
+		for j := 0; j < 8; j++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}()
+
+// oggCRC computes the CRC-32 of a page whose checksum field is zeroed.
+func oggCRC(page []byte) uint32 {
+	var crc uint32
+	for _, b := range page {
+		crc = crc<<8 ^ oggCRCTable[byte(crc>>24)^b]
+	}
+	return crc
+}
+
+// OggChunker yields Ogg chunks made of whole pages, for any codec such as
+// Vorbis, Opus or FLAC. The first chunk holds at least the header pages:
+// the beginning-of-stream pages and the pages following them up to the
+// first one with a non-zero granule position, which carry the codec
+// headers decoders need for the pages of the following chunks.
+type OggChunker struct {
+	chunkStats
+	r          *bufio.Reader
+	targetSize int
+	metrics    Metrics
+	started    bool   // the header pages were read
+	page       []byte // page read ahead that did not fit into the last chunk
+	err        error
+}
+
+// NewOggChunker returns a new OggChunker that reads from r.
+func NewOggChunker(r io.Reader, chunkSize int, opts ...Option) *OggChunker {
+	o := newOptions(opts)
+	return &OggChunker{
+		r:          bufio.NewReader(o.reader(r)),
+		targetSize: chunkSize,
+		metrics:    o.metrics,
+		chunkStats: chunkStats{maxChunks: o.maxChunks},
+		err:        validateChunkSize(chunkSize),
+	}
+}
+
+// Next returns the next chunk or io.EOF when done.
+func (c *OggChunker) Next() ([]byte, error) {
+	if c.exhausted() {
+		return nil, io.EOF
+	}
+	chunk, err := c.next()
+	c.count(chunk, err)
+	return observe(c.metrics, chunk, err)
+}
+
+// next groups whole pages into a chunk of at most the target size, unless
+// a single page or the header pages are larger.
+func (c *OggChunker) next() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	var chunk []byte
+	headers := !c.started
+	c.started = true
+	for pages := 0; ; pages++ {
+		if c.page == nil {
+			page, err := c.readPage()
+			if err != nil {
+				c.err = err
+				if len(chunk) > 0 {
+					return chunk, nil
+				}
+				return nil, err
+			}
+			c.page = page
+		}
+		if headers && !isOggHeaderPage(c.page, pages) {
+			headers = false
+		}
+		if !headers && pages > 0 && len(chunk)+len(c.page) > c.targetSize {
+			return chunk, nil
+		}
+		chunk = append(chunk, c.page...)
+		c.page = nil
+	}
+}
+
+// isOggHeaderPage reports whether the page, found at the given index at
+// the start of the stream, holds codec headers.
+func isOggHeaderPage(page []byte, index int) bool {
+	return page[5]&oggFlagBOS != 0 || index > 0 && binary.LittleEndian.Uint64(page[6:14]) == 0
+}
+
+// readPage reads the next page and checks its CRC-32.
+func (c *OggChunker) readPage() ([]byte, error) {
+	var hdr [oggHeaderSize]byte
+	if _, err := io.ReadFull(c.r, hdr[:]); err != nil {
+		return nil, err
+	}
+	if !compareID(hdr[0:4], "OggS") || hdr[4] != 0 {
+		return nil, ErrInvalidOggPage
+	}
+	segments := int(hdr[26])
+	page := make([]byte, oggHeaderSize+segments, oggHeaderSize+segments+segments*255)
+	copy(page, hdr[:])
+	if _, err := io.ReadFull(c.r, page[oggHeaderSize:]); err != nil {
+		return nil, noEOF(err)
+	}
+	size := 0
+	for _, n := range page[oggHeaderSize:] {
+		size += int(n)
+	}
+	page = page[:len(page)+size]
+	if _, err := io.ReadFull(c.r, page[len(page)-size:]); err != nil {
+		return nil, noEOF(err)
+	}
+
+	want := binary.LittleEndian.Uint32(page[22:26])
+	clear(page[22:26])
+	crc := oggCRC(page)
+	binary.LittleEndian.PutUint32(page[22:26], want)
+	if crc != want {
+		return nil, ErrInvalidOggPage
+	}
+	return page, nil
+}
+
+// Close implements Chunker. An OggChunker holds no resources, so it is a
+// no-op; closing the input is up to the caller.
+func (c *OggChunker) Close() error {
+	return nil
+}
+
+// IndependentChunks reports whether every chunk can be decoded in isolation.
+// Only the first chunk carries the codec headers, so it returns false.
+func (c *OggChunker) IndependentChunks() bool {
+	return false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+)
+
+// makeOggPage returns a page holding body as a single packet.
+func makeOggPage(flags byte, granule uint64, seq uint32, body []byte) []byte {
+	page := make([]byte, oggHeaderSize, oggHeaderSize+len(body)/255+1+len(body))
+	copy(page, "OggS")
+	page[5] = flags
+	binary.LittleEndian.PutUint64(page[6:14], granule)
+	binary.LittleEndian.PutUint32(page[14:18], 0x1234)
+	binary.LittleEndian.PutUint32(page[18:22], seq)
+	for n := len(body); ; n -= 255 {
+		page[26]++
+		if n < 255 {
+			page = append(page, byte(n))
+			break
+		}
+		page = append(page, 255)
+	}
+	page = append(page, body...)
+	binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+	return page
+}
+
+func TestOggChunker(t *testing.T) {
+	pages := [][]byte{
+		makeOggPage(oggFlagBOS, 0, 0, makeAudio(1500, 1)),
+		makeOggPage(0, 0, 1, makeAudio(1200, 2)),
+	}
+	for i := 0; i < 8; i++ {
+		pages = append(pages, makeOggPage(0, uint64(960*(i+1)), uint32(i+2), makeAudio(600, byte(i))))
+	}
+	stream := bytes.Join(pages, nil)
+	headerLen := len(pages[0]) + len(pages[1])
+
+	chunks := readAllChunks(t, NewOggChunker(bytes.NewReader(stream), 2000))
+	if !bytes.Equal(bytes.Join(chunks, nil), stream) {
+		t.Fatal("chunks do not join to the input")
+	}
+	// The header pages exceed the chunk size but stay together
+	if len(chunks[0]) != headerLen {
+		t.Errorf("first chunk has %d bytes, want the %d bytes of the header pages", len(chunks[0]), headerLen)
+	}
+	for i, chunk := range chunks[1:] {
+		if len(chunk) > 2000 || len(chunk)%len(pages[2]) != 0 {
+			t.Errorf("chunk %d: %d bytes are not whole pages within the chunk size", i+1, len(chunk))
+		}
+	}
+
+	// A corrupt page fails after the pages before it
+	corrupt := append([]byte(nil), stream...)
+	corrupt[headerLen+len(pages[2])+40] ^= 0x01
+	c := NewOggChunker(bytes.NewReader(corrupt), 1<<20)
+	if chunk, err := c.Next(); err != nil || len(chunk) != headerLen+len(pages[2]) {
+		t.Fatalf("Next() = %d bytes, %v; want the pages before the corrupt one", len(chunk), err)
+	}
+	if _, err := c.Next(); err != ErrInvalidOggPage {
+		t.Fatalf("Next() error = %v, want ErrInvalidOggPage", err)
+	}
+
+	// Truncated pages are reported as such
+	c = NewOggChunker(bytes.NewReader(stream[:len(pages[0])-10]), 2000)
+	if _, err := c.Next(); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Next() error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
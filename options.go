@@ -0,0 +1,333 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"hash"
+	"io"
+	"sync"
+	"time"
+)
+
+// Option configures optional chunker behaviour.
+// Options that do not apply to a given chunker are ignored by it.
+type Option func(*options)
+
+// options holds the settings collected from a list of Option values.
+type options struct {
+	continuous       bool
+	chunkSize        int
+	wavMode          WAVChunkMode
+	noPooling        bool
+	headerPool       *sync.Pool
+	audioPool        *sync.Pool
+	finalChunkPolicy FinalChunkPolicy
+	reuseBuffer      bool
+	maxChunks        int
+	framesPerChunk   int
+	lenientEmphasis  bool
+	conceal          bool
+	prependTags      bool
+	gzipLevel        int
+	planar           bool
+	trimSilence      bool
+	metrics          Metrics
+	strict           bool
+	padOdd           bool
+	maxMetaChunks    int
+	silenceWindow    time.Duration
+	silenceLevel     float64
+	segmentSilence   time.Duration
+	segmentGain      int
+	bufferSize       int
+	bextTimeRef      bool
+	noMetadata       bool
+	hash             hash.Hash
+	headerTimeout    time.Duration
+	wrappers         []func(io.Reader) io.Reader
+}
+
+// newOptions applies opts on top of the defaults.
+func newOptions(opts []Option) options {
+	o := options{
+		headerPool:    &headerBufferPool,
+		audioPool:     &audioBufferPool,
+		metrics:       nopMetrics{},
+		maxMetaChunks: defaultMaxMetadataChunks,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// validate reports settings that cannot be honoured, so that they are
+// rejected before any chunking begins.
+func (o *options) validate() error {
+	if !validGzipLevel(o.gzipLevel) {
+		return fmt.Errorf("invalid gzip compression level %d: must be between %d and %d",
+			o.gzipLevel, gzip.HuffmanOnly, gzip.BestCompression)
+	}
+	return nil
+}
+
+// reader wraps r with the configured reader wrappers.
+func (o *options) reader(r io.Reader) io.Reader {
+	for _, wrap := range o.wrappers {
+		r = wrap(r)
+	}
+	return r
+}
+
+// WithContinuous makes a WAVPlaylistChunker treat the audio of all files
+// as a single stream wrapped with the header of the first file.
+func WithContinuous() Option {
+	return func(o *options) {
+		o.continuous = true
+	}
+}
+
+// WithChunkSize sets the chunk size of a WAVChunker, including the header
+// of complete-mode chunks. Sizes outside [MinChunkSize, MaxChunkSize] are
+// ignored in favour of DefaultChunkSize.
+func WithChunkSize(n int) Option {
+	return func(o *options) {
+		o.chunkSize = n
+	}
+}
+
+// WithWAVMode sets the chunk mode of a WAVChunker.
+func WithWAVMode(mode WAVChunkMode) Option {
+	return func(o *options) {
+		o.wavMode = mode
+	}
+}
+
+// WithoutPooling makes a WAVChunker allocate fresh buffers instead of
+// reusing pooled ones. It is meant for tests hunting aliasing bugs.
+func WithoutPooling() Option {
+	return func(o *options) {
+		o.noPooling = true
+	}
+}
+
+// WithFinalChunkPolicy sets how a DumbChunker handles a short final chunk.
+func WithFinalChunkPolicy(p FinalChunkPolicy) Option {
+	return func(o *options) {
+		o.finalChunkPolicy = p
+	}
+}
+
+// WithReuseBuffer makes a DumbChunker read every chunk into the same
+// buffer instead of allocating one per chunk, which saves an allocation
+// per chunk on large inputs. The slice returned by Next aliases that
+// buffer: it is only valid until the next call to Next and must be copied
+// to be retained.
+func WithReuseBuffer() Option {
+	return func(o *options) {
+		o.reuseBuffer = true
+	}
+}
+
+// WithMaxChunks makes Next return io.EOF once n chunks were returned, even
+// if the input holds more, e.g. to preview the start of a stream. Every
+// chunk counts, including a first chunk carrying a WAV header or an MP3
+// chunk carrying bit reservoir. A count of 0 sets no limit.
+func WithMaxChunks(n int) Option {
+	return func(o *options) {
+		o.maxChunks = n
+	}
+}
+
+// WithStreamHash feeds every byte read from the input into h, including
+// bytes skipped by the chunker, so once Next returns io.EOF h holds the
+// digest of the whole input.
+func WithStreamHash(h hash.Hash) Option {
+	return func(o *options) {
+		o.hash = h
+		o.wrappers = append(o.wrappers, func(r io.Reader) io.Reader {
+			return io.TeeReader(r, h)
+		})
+	}
+}
+
+// WithFramesPerChunk makes an MP3Chunker put exactly n frames into every
+// chunk but the last one, regardless of the chunk size.
+func WithFramesPerChunk(n int) Option {
+	return func(o *options) {
+		o.framesPerChunk = n
+	}
+}
+
+// WithPools makes a WAVChunker take its header and audio buffers from the
+// given pools instead of the package-wide ones. The pools must return
+// []byte values, like the package-wide pools do. A nil pool keeps the default.
+func WithPools(header, audio *sync.Pool) Option {
+	return func(o *options) {
+		if header != nil {
+			o.headerPool = header
+		}
+		if audio != nil {
+			o.audioPool = audio
+		}
+	}
+}
+
+// WithLenientEmphasis makes an MP3Chunker accept frames with the reserved
+// emphasis value, which some encoders set by mistake. By default such
+// frames are rejected as invalid.
+func WithLenientEmphasis() Option {
+	return func(o *options) {
+		o.lenientEmphasis = true
+	}
+}
+
+// WithErrorConcealment makes an MP3Chunker replace frames whose body is
+// truncated or fails the CRC check with silent frames of the same format
+// and length, so the timeline of the stream stays intact.
+func WithErrorConcealment() Option {
+	return func(o *options) {
+		o.conceal = true
+	}
+}
+
+// WithHeaderTimeout bounds how long a WAVChunker may spend parsing the
+// header, failing with ErrHeaderTimeout when exceeded. This protects
+// against inputs trickling in the header bytes slowly.
+func WithHeaderTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.headerTimeout = d
+	}
+}
+
+// WithPrependTags makes an MP3Chunker start the first chunk with the
+// leading ID3v2 tag of the stream, so the chunk alone is a playable file
+// carrying the metadata. Later chunks never include the tag.
+func WithPrependTags() Option {
+	return func(o *options) {
+		o.prependTags = true
+	}
+}
+
+// WithGzipLevel makes a WAVChunker compress every chunk into a gzip stream
+// of its own, so chunks can be decompressed individually. The level ranges
+// from gzip.HuffmanOnly to gzip.BestCompression; NewChunker rejects other
+// levels. The default, gzip.NoCompression, leaves the chunks uncompressed.
+func WithGzipLevel(level int) Option {
+	return func(o *options) {
+		o.gzipLevel = level
+	}
+}
+
+// WithTrimTrailingSilence makes an MP3Chunker drop the trailing frames
+// that only hold the encoder padding declared by the LAME tag, so the
+// output ends at the real audio. Only whole frames are dropped.
+func WithTrimTrailingSilence() Option {
+	return func(o *options) {
+		o.trimSilence = true
+	}
+}
+
+// WithPlanarOutput makes a headerless WAVChunker de-interleave the audio
+// of every chunk: all samples of the first channel, then all samples of
+// the second one, and so on. Chunks hold whole sample frames. Planar audio
+// is not valid WAV data, so the option is ignored in the other modes.
+func WithPlanarOutput() Option {
+	return func(o *options) {
+		o.planar = true
+	}
+}
+
+// WithMetrics makes a chunker report the chunks it returns, the bytes it
+// reads and the errors it fails with to m.
+func WithMetrics(m Metrics) Option {
+	return func(o *options) {
+		o.metrics = m
+		o.wrappers = append(o.wrappers, func(r io.Reader) io.Reader {
+			return &countingReader{r: r, m: m}
+		})
+	}
+}
+
+// WithStrict makes a WAVChunker reject headers whose sizes contradict each
+// other instead of ignoring the sizes it does not rely on.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithPadOddChunks makes a WAVChunker append the pad byte RIFF requires
+// after a data chunk of odd size, as happens with 8-bit mono audio, so
+// every chunk is a strictly conformant WAV file. The data size still
+// reports the odd audio length.
+func WithPadOddChunks() Option {
+	return func(o *options) {
+		o.padOdd = true
+	}
+}
+
+// WithSilenceAwareBoundaries makes a PCM WAVChunker cut every chunk at
+// the quietest point within the last window of audio before the chunk
+// size, provided its RMS level, relative to full scale, is at most
+// threshold. Chunks then tend to end in pauses between words, at the cost
+// of varying in size. Audio past the cut starts the next chunk.
+func WithSilenceAwareBoundaries(window time.Duration, threshold float64) Option {
+	return func(o *options) {
+		o.silenceWindow = window
+		o.silenceLevel = threshold
+	}
+}
+
+// WithSilenceSegmentation makes an MP3Chunker end a chunk as soon as a run
+// of silent frames lasts minSilence, so chunks follow the natural breaks
+// of the audio, e.g. for podcast chapters. A Layer III frame counts as
+// silent when the global gain of all its granules is at most maxGain; this
+// reads the side information only and never decodes the audio. The chunk
+// size still bounds chunks without pauses.
+func WithSilenceSegmentation(minSilence time.Duration, maxGain int) Option {
+	return func(o *options) {
+		o.segmentSilence = minSilence
+		o.segmentGain = maxGain
+	}
+}
+
+// WithMaxMetadataChunks limits how many chunks may precede the data chunk
+// of a WAV file, failing with ErrTooManyChunks beyond that. The default
+// limit is 1024; n <= 0 removes it, leaving only the header size limit.
+func WithMaxMetadataChunks(n int) Option {
+	return func(o *options) {
+		o.maxMetaChunks = max(n, 0)
+	}
+}
+
+// WithBufferSize sets how many chunks Stream may hold in its channel ahead
+// of the consumer. n <= 0 makes the channel unbuffered.
+func WithBufferSize(n int) Option {
+	return func(o *options) {
+		o.bufferSize = max(n, 0)
+	}
+}
+
+// WithBextTimeReference makes a complete-mode WAVChunker give every chunk
+// a bext chunk whose time reference is the position of its first sample,
+// so segments keep their place on the timeline. Positions start at the
+// time reference of the source bext chunk, if any, which is reused;
+// otherwise an empty bext chunk is added.
+func WithBextTimeReference() Option {
+	return func(o *options) {
+		o.bextTimeRef = true
+	}
+}
+
+// WithMetadata sets whether every complete-mode chunk of a WAVChunker
+// carries the metadata chunks of the input, such as LIST/INFO, which is
+// the default. With include false only the first chunk does, the others
+// get a minimal header holding just the fmt chunk, which is the canonical
+// 44-byte header for PCM audio. The bext chunk stays when it carries the
+// time reference set by WithBextTimeReference.
+func WithMetadata(include bool) Option {
+	return func(o *options) {
+		o.noMetadata = !include
+	}
+}
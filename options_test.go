@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestWithStreamHash(t *testing.T) {
+	mp3, err := os.ReadFile("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wav := append(makeWAV(2, 44100, 16, makeAudio(30001, 0x24)), "LIST\x04\x00\x00\x00INFO"...)
+
+	tests := []struct {
+		name  string
+		input []byte
+		new   func(r *bytes.Reader, opt Option) Chunker
+	}{
+		{"mp3", mp3, func(r *bytes.Reader, opt Option) Chunker { return NewMP3Chunker(r, 8192, 511, opt) }},
+		{"wav", wav, func(r *bytes.Reader, opt Option) Chunker { return NewWAVChunker(r, opt) }},
+		{"dumb", wav, func(r *bytes.Reader, opt Option) Chunker { return NewDumbChunker(r, 1024, opt) }},
+	}
+
+	for _, tt := range tests {
+		h := sha256.New()
+		readAllChunks(t, tt.new(bytes.NewReader(tt.input), WithStreamHash(h)))
+
+		if got, want := h.Sum(nil), sha256.Sum256(tt.input); !bytes.Equal(got, want[:]) {
+			t.Errorf("%s: stream hash %x, want %x", tt.name, got, want)
+		}
+	}
+}
+
+func TestWithMaxChunks(t *testing.T) {
+	wav, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp3, err := os.ReadFile("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]func(opts ...Option) Chunker{
+		"wav": func(opts ...Option) Chunker {
+			return NewWAVChunker(bytes.NewReader(wav), append(opts, WithChunkSize(8192))...)
+		},
+		"wav streaming": func(opts ...Option) Chunker {
+			return NewWAVChunker(bytes.NewReader(wav), append(opts, WithChunkSize(8192), WithWAVMode(WAVModeStreaming))...)
+		},
+		"mp3": func(opts ...Option) Chunker {
+			return NewMP3Chunker(bytes.NewReader(mp3), 8192, maxReservoir, opts...)
+		},
+		"dumb": func(opts ...Option) Chunker {
+			return NewDumbChunker(bytes.NewReader(wav), 8192, opts...)
+		},
+	}
+	for name, newChunker := range tests {
+		all := readAllChunks(t, newChunker())
+		got := readAllChunks(t, newChunker(WithMaxChunks(3)))
+		if len(got) != 3 {
+			t.Fatalf("%s: got %d chunks, want 3", name, len(got))
+		}
+		// The chunks are those of an unlimited chunker
+		for i := range got {
+			if !bytes.Equal(got[i], all[i]) {
+				t.Errorf("%s: chunk %d differs from the unlimited one", name, i)
+			}
+		}
+	}
+}
+
+func TestWAVChunkerMaxChunksReleasesBuffers(t *testing.T) {
+	wav := makeWAV(2, 44100, 16, makeAudio(50000, 0x3d))
+	c := NewWAVChunker(bytes.NewReader(wav), WithChunkSize(8192), WithMaxChunks(2))
+	for i := 0; i < 2; i++ {
+		if _, err := c.Next(); err != nil {
+			t.Fatalf("Next() %d error: %v", i, err)
+		}
+	}
+	if n, err := c.NextSize(); err != io.EOF {
+		t.Fatalf("NextSize() at the limit = %d, %v, want io.EOF", n, err)
+	}
+	if chunk, err := c.Next(); err != io.EOF {
+		t.Fatalf("Next() at the limit = %d bytes, %v, want io.EOF", len(chunk), err)
+	}
+	if !c.closed || c.audio != nil || c.header != nil {
+		t.Error("buffers kept after reaching the limit")
+	}
+}
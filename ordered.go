@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrReorderWindowExceeded is returned by OrderedCollector when more chunks
+// are waiting for a missing one than the reorder window allows.
+var ErrReorderWindowExceeded = errors.New("reorder window exceeded")
+
+// IndexedChunk is a chunk tagged with its position in the stream,
+// as produced by parallel chunkers.
+type IndexedChunk struct {
+	Index int
+	Data  []byte
+}
+
+// OrderedCollector reads chunks arriving in any order from in and passes
+// them to out in index order, starting at index 0. At most window chunks
+// are buffered while waiting for a missing one; exceeding it fails with
+// ErrReorderWindowExceeded. It returns once in is closed, or on the first
+// error returned by out.
+func OrderedCollector(in <-chan IndexedChunk, window int, out func([]byte) error) error {
+	pending := make(map[int][]byte)
+	next := 0
+
+	for c := range in {
+		if _, dup := pending[c.Index]; dup || c.Index < next {
+			return fmt.Errorf("duplicate chunk %d", c.Index)
+		}
+		pending[c.Index] = c.Data
+
+		for {
+			data, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			if err := out(data); err != nil {
+				return err
+			}
+			next++
+		}
+		// Only chunks still waiting for a missing one count
+		if len(pending) > window {
+			return fmt.Errorf("%w: waiting for chunk %d", ErrReorderWindowExceeded, next)
+		}
+	}
+
+	if len(pending) != 0 {
+		return fmt.Errorf("missing chunk %d", next)
+	}
+	return nil
+}
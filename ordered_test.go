@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func TestOrderedCollector(t *testing.T) {
+	const n = 100
+
+	in := make(chan IndexedChunk, n)
+	for _, i := range rand.New(rand.NewSource(1)).Perm(n) {
+		in <- IndexedChunk{Index: i, Data: []byte{byte(i)}}
+	}
+	close(in)
+
+	var got []byte
+	err := OrderedCollector(in, n, func(data []byte) error {
+		got = append(got, data...)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("OrderedCollector() error: %v", err)
+	}
+	for i := 0; i < n; i++ {
+		if got[i] != byte(i) {
+			t.Fatalf("chunk %d delivered out of order: %v", i, got)
+		}
+	}
+}
+
+func TestOrderedCollectorWindowExceeded(t *testing.T) {
+	in := make(chan IndexedChunk, 4)
+	for _, i := range []int{1, 2, 3, 4} { // chunk 0 never arrives
+		in <- IndexedChunk{Index: i, Data: []byte{byte(i)}}
+	}
+	close(in)
+
+	var got bytes.Buffer
+	err := OrderedCollector(in, 3, func(data []byte) error {
+		got.Write(data)
+		return nil
+	})
+	if !errors.Is(err, ErrReorderWindowExceeded) {
+		t.Fatalf("got %v, want %v", err, ErrReorderWindowExceeded)
+	}
+	if got.Len() != 0 {
+		t.Errorf("delivered %d chunks before chunk 0", got.Len())
+	}
+}
+
+func TestOrderedCollectorWindowBounds(t *testing.T) {
+	tests := []struct {
+		window  int
+		indexes []int
+	}{
+		{1, []int{1, 0, 3, 2}}, // one chunk waits for the one before it
+		{0, []int{0, 1, 2, 3}}, // no chunk ever waits
+	}
+	for _, tt := range tests {
+		in := make(chan IndexedChunk, len(tt.indexes))
+		for _, i := range tt.indexes {
+			in <- IndexedChunk{Index: i, Data: []byte{byte(i)}}
+		}
+		close(in)
+
+		var got []byte
+		err := OrderedCollector(in, tt.window, func(data []byte) error {
+			got = append(got, data...)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("window %d, indexes %v: OrderedCollector() error: %v", tt.window, tt.indexes, err)
+		}
+		if !bytes.Equal(got, []byte{0, 1, 2, 3}) {
+			t.Errorf("window %d, indexes %v: delivered %v", tt.window, tt.indexes, got)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Output formats of the command, selected with -output.
+const (
+	outputJSON   = "json"   // a JSON object with the base64-encoded chunk per line
+	outputRaw    = "raw"    // the chunks back to back
+	outputFramed = "framed" // every chunk preceded by its length
+)
+
+// chunkWriter encodes the chunks written by the command.
+type chunkWriter interface {
+	WriteChunk(chunk []byte) error
+}
+
+// newChunkWriter returns the chunkWriter of the given output format. A
+// non-empty checksum adds the digest of every chunk, which only the JSON
+// format can carry.
+func newChunkWriter(format, checksum string, w io.Writer) (chunkWriter, error) {
+	if !validChecksum(checksum) {
+		return nil, fmt.Errorf("unknown checksum %q: must be %s or %s", checksum, checksumSHA256, checksumCRC32)
+	}
+	if checksum != "" && format != outputJSON {
+		return nil, fmt.Errorf("checksums need the %s output format", outputJSON)
+	}
+	switch format {
+	case outputJSON:
+		return &jsonChunkWriter{enc: json.NewEncoder(w), checksum: checksum}, nil
+	case outputRaw:
+		return rawChunkWriter{w}, nil
+	case outputFramed:
+		return framedChunkWriter{w}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q: must be %s, %s or %s", format, outputJSON, outputRaw, outputFramed)
+}
+
+// writeChunksAs writes every chunk of c to cw.
+func writeChunksAs(cw chunkWriter, c Chunker) error {
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := cw.WriteChunk(chunk); err != nil {
+			return err
+		}
+	}
+}
+
+// writeFileChunksAs writes every chunk of c, read from the named file, to
+// cw like writeChunksAs, tagging the chunks with the file name and their
+// index within the file if the format of cw allows for it.
+func writeFileChunksAs(cw chunkWriter, name string, c Chunker) error {
+	if t, ok := cw.(fileTagger); ok {
+		t.startFile(name)
+	}
+	return writeChunksAs(cw, c)
+}
+
+// fileTagger is implemented by chunk writers that tag the chunks with the
+// file they were read from.
+type fileTagger interface {
+	startFile(name string)
+}
+
+// fileErrorWriter is implemented by chunk writers that record the failure
+// of a file in the output.
+type fileErrorWriter interface {
+	writeFileError(e FileError) error
+}
+
+// writeFiles writes the chunks of the named files, each opened with open,
+// to cw like writeFileChunksAs. A file that cannot be chunked does not
+// stop the others: its error is collected and, if interleave is set and
+// cw allows for it, also recorded in the output. Chunks written before the
+// failure are kept. done, if non-nil, is called with every file chunked
+// successfully once it is closed. The error is that of writing the output,
+// which stops the remaining files.
+func writeFiles(cw chunkWriter, names []string, open func(name string) (Chunker, error), done func(name string, c Chunker), interleave bool) ([]FileError, error) {
+	var errs []FileError
+	for _, name := range names {
+		c, err := open(name)
+		if err == nil {
+			src := &sourceChunker{Chunker: c}
+			err = writeFileChunksAs(cw, name, src)
+			c.Close()
+			if err != nil && src.err == nil {
+				return errs, err
+			}
+		}
+		if err == nil {
+			if done != nil {
+				done(name, c)
+			}
+			continue
+		}
+		e := FileError{Name: name, Err: err}
+		errs = append(errs, e)
+		if w, ok := cw.(fileErrorWriter); ok && interleave {
+			if err := w.writeFileError(e); err != nil {
+				return errs, err
+			}
+		}
+	}
+	return errs, nil
+}
+
+// sourceChunker records the error of the Chunker it wraps, telling it
+// apart from the errors of writing its chunks.
+type sourceChunker struct {
+	Chunker
+	err error
+}
+
+func (c *sourceChunker) Next() ([]byte, error) {
+	chunk, err := c.Chunker.Next()
+	if err != nil && err != io.EOF {
+		c.err = err
+	}
+	return chunk, err
+}
+
+// jsonChunkWriter writes every chunk as a DataChunk line along with its
+// digest if checksum is set. Once a file was started the lines are
+// FileDataChunks. If meta is set, the lines are the objects it returns
+// with the "data" field set instead.
+type jsonChunkWriter struct {
+	enc      *json.Encoder
+	checksum string
+	meta     ChunkMetaFunc
+	file     string
+	index    int
+}
+
+func (w *jsonChunkWriter) WriteChunk(chunk []byte) error {
+	v := DataChunk{Data: base64.StdEncoding.EncodeToString(chunk)}
+	addChecksum(&v, w.checksum, chunk)
+	w.index++
+	switch {
+	case w.meta != nil:
+		obj := w.meta(w.index-1, chunk)
+		if obj == nil {
+			obj = make(map[string]any, 1)
+		}
+		obj["data"] = v.Data
+		return w.enc.Encode(obj)
+	case w.file != "":
+		return w.enc.Encode(FileDataChunk{File: w.file, Index: w.index - 1, DataChunk: v})
+	}
+	return w.enc.Encode(v)
+}
+
+func (w *jsonChunkWriter) startFile(name string) {
+	w.file, w.index = name, 0
+}
+
+func (w *jsonChunkWriter) writeFileError(e FileError) error {
+	return w.enc.Encode(errorObject{File: e.Name, Error: e.Err.Error()})
+}
+
+// rawChunkWriter writes the chunks back to back, losing their boundaries.
+type rawChunkWriter struct {
+	w io.Writer
+}
+
+func (w rawChunkWriter) WriteChunk(chunk []byte) error {
+	_, err := w.w.Write(chunk)
+	return err
+}
+
+// framedChunkWriter writes every chunk preceded by its length as a 4-byte
+// big-endian integer, so the boundaries can be recovered without the
+// base64 overhead of JSON.
+type framedChunkWriter struct {
+	w io.Writer
+}
+
+func (w framedChunkWriter) WriteChunk(chunk []byte) error {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(chunk)))
+	if _, err := w.w.Write(n[:]); err != nil {
+		return err
+	}
+	_, err := w.w.Write(chunk)
+	return err
+}
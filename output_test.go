@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+)
+
+func TestChunkWriter(t *testing.T) {
+	chunks := [][]byte{[]byte("abc"), {}, []byte("de")}
+
+	tests := map[string]string{
+		outputJSON:   "{\"data\":\"YWJj\"}\n{\"data\":\"\"}\n{\"data\":\"ZGU=\"}\n",
+		outputRaw:    "abcde",
+		outputFramed: "\x00\x00\x00\x03abc\x00\x00\x00\x00\x00\x00\x00\x02de",
+	}
+	for format, want := range tests {
+		var buf bytes.Buffer
+		cw, err := newChunkWriter(format, "", &buf)
+		if err != nil {
+			t.Fatalf("%s: newChunkWriter() error: %v", format, err)
+		}
+		if err := writeChunksAs(cw, &sliceChunker{chunks: chunks, err: io.EOF}); err != nil {
+			t.Fatalf("%s: writeChunksAs() error: %v", format, err)
+		}
+		if got := buf.String(); got != want {
+			t.Errorf("%s: got %q, want %q", format, got, want)
+		}
+	}
+
+	if _, err := newChunkWriter("yaml", "", &bytes.Buffer{}); err == nil {
+		t.Error("newChunkWriter() accepted an unknown format")
+	}
+}
+
+func TestChunkWriterChecksumOptions(t *testing.T) {
+	if _, err := newChunkWriter(outputJSON, "md5", &bytes.Buffer{}); err == nil {
+		t.Error("newChunkWriter() accepted an unknown checksum")
+	}
+	if _, err := newChunkWriter(outputRaw, checksumSHA256, &bytes.Buffer{}); err == nil {
+		t.Error("newChunkWriter() accepted a checksum for raw output")
+	}
+}
+
+func TestWriteFileChunksAs(t *testing.T) {
+	var buf bytes.Buffer
+	cw, err := newChunkWriter(outputJSON, "", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string][][]byte{
+		"a.wav": {[]byte("abc"), []byte("de")},
+		"b.mp3": {[]byte("f")},
+	}
+	for _, name := range []string{"a.wav", "b.mp3"} {
+		if err := writeFileChunksAs(cw, name, &sliceChunker{chunks: files[name], err: io.EOF}); err != nil {
+			t.Fatalf("%s: writeFileChunksAs() error: %v", name, err)
+		}
+	}
+
+	want := `{"file":"a.wav","index":0,"data":"YWJj"}
+{"file":"a.wav","index":1,"data":"ZGU="}
+{"file":"b.mp3","index":0,"data":"Zg=="}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// Raw output has no room for the tags
+	buf.Reset()
+	if cw, err = newChunkWriter(outputRaw, "", &buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileChunksAs(cw, "a.wav", &sliceChunker{chunks: files["a.wav"], err: io.EOF}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "abcde" {
+		t.Errorf("raw: got %q, want %q", got, "abcde")
+	}
+}
+
+func TestWriteFiles(t *testing.T) {
+	errBroken := errors.New("broken")
+	files := map[string]*sliceChunker{
+		"a.wav": {chunks: [][]byte{[]byte("abc")}, err: errBroken},
+		"b.mp3": {chunks: [][]byte{[]byte("f")}, err: io.EOF},
+	}
+	open := func(name string) (Chunker, error) {
+		if c, ok := files[name]; ok {
+			return c, nil
+		}
+		return nil, fs.ErrNotExist
+	}
+
+	var buf bytes.Buffer
+	cw, err := newChunkWriter(outputJSON, "", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var done []string
+	errs, err := writeFiles(cw, []string{"a.wav", "c.ogg", "b.mp3"}, open, func(name string, _ Chunker) {
+		done = append(done, name)
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(errs) != 2 || !errors.Is(errs[0], errBroken) || !errors.Is(errs[1], fs.ErrNotExist) {
+		t.Fatalf("got errors %v, want a.wav and c.ogg", errs)
+	}
+	if len(done) != 1 || done[0] != "b.mp3" {
+		t.Errorf("got done files %v, want b.mp3", done)
+	}
+	want := `{"file":"a.wav","index":0,"data":"YWJj"}
+{"file":"a.wav","error":"broken"}
+{"file":"c.ogg","error":"file does not exist"}
+{"file":"b.mp3","index":0,"data":"Zg=="}
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// A failed write of the output stops the remaining files
+	files["a.wav"] = &sliceChunker{chunks: [][]byte{[]byte("abc")}, err: io.EOF}
+	errs, err = writeFiles(rawChunkWriter{failingWriter{errBroken}}, []string{"a.wav", "b.mp3"}, open, nil, true)
+	if err != errBroken || len(errs) != 0 {
+		t.Errorf("got %v, %v, want no file errors and %v", errs, err, errBroken)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ParallelDumbChunker splits input of a known size into fixed-size chunks
+// like a DumbChunker, the last chunk being short. As the chunk boundaries
+// are purely positional, any chunk can be read directly at its offset, so
+// chunks can be read concurrently. Stats counts the chunks passed on by
+// ReadParallel.
+type ParallelDumbChunker struct {
+	chunkStats
+	r          io.ReaderAt
+	size       int64
+	targetSize int
+	err        error
+}
+
+// NewParallelDumbChunker returns a new ParallelDumbChunker that reads the
+// size bytes of r. For a chunk size outside [MinChunkSize, MaxChunkSize],
+// ChunkAt and ReadParallel fail with ErrInvalidChunkSize.
+func NewParallelDumbChunker(r io.ReaderAt, size int64, chunkSize int) *ParallelDumbChunker {
+	return &ParallelDumbChunker{
+		r:          r,
+		size:       size,
+		targetSize: chunkSize,
+		err:        validateChunkSize(chunkSize),
+	}
+}
+
+// NumChunks returns the number of chunks of the input, or 0 for an invalid
+// chunk size.
+func (c *ParallelDumbChunker) NumChunks() int {
+	if c.err != nil {
+		return 0
+	}
+	return int((c.size + int64(c.targetSize) - 1) / int64(c.targetSize))
+}
+
+// ChunkAt reads the chunk with index i. It is safe for concurrent use if
+// the ReadAt method of the input is, as that of *os.File.
+func (c *ParallelDumbChunker) ChunkAt(i int) ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if i < 0 || i >= c.NumChunks() {
+		return nil, fmt.Errorf("chunk %d out of range [0, %d)", i, c.NumChunks())
+	}
+	off := int64(i) * int64(c.targetSize)
+	chunk := make([]byte, min(int64(c.targetSize), c.size-off))
+	n, err := c.r.ReadAt(chunk, off)
+	if n == len(chunk) {
+		return chunk, nil
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF // the input is shorter than its size
+	}
+	return nil, fmt.Errorf("chunk %d: %w", i, err)
+}
+
+// ReadParallel reads the chunks with the given number of workers and passes
+// them to out in order, through an OrderedCollector. At most twice as many
+// chunks as workers are read ahead of the chunk out waits for. It returns
+// the first error of a read or of out, after all workers have stopped.
+func (c *ParallelDumbChunker) ReadParallel(workers int, out func([]byte) error) error {
+	if c.err != nil {
+		return c.err
+	}
+	workers = max(workers, 1)
+	window := 2 * workers
+
+	indexes := make(chan int)
+	results := make(chan IndexedChunk, workers)
+	tokens := make(chan struct{}, window) // chunks read but not passed to out
+	done := make(chan struct{})
+
+	var once sync.Once
+	var failure error
+	stop := func(err error) {
+		once.Do(func() {
+			failure = err
+			close(done)
+		})
+	}
+
+	go func() {
+		defer close(indexes)
+		for i := 0; i < c.NumChunks(); i++ {
+			select {
+			case tokens <- struct{}{}:
+			case <-done:
+				return
+			}
+			select {
+			case indexes <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				chunk, err := c.ChunkAt(i)
+				if err != nil {
+					stop(err)
+					return
+				}
+				select {
+				case results <- IndexedChunk{Index: i, Data: chunk}:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	err := OrderedCollector(results, window, func(chunk []byte) error {
+		<-tokens
+		c.count(chunk, nil)
+		return out(chunk)
+	})
+	// A failed read stopped the workers first and is the cause of err
+	stop(err)
+	for range results {
+	}
+	return failure
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestParallelDumbChunker(t *testing.T) {
+	data := makeAudio(100000, 0x21)
+	for _, size := range []int{1024, 4096, 100000, 150000} {
+		want := readAllChunks(t, NewDumbChunker(bytes.NewReader(data), size))
+
+		c := NewParallelDumbChunker(bytes.NewReader(data), int64(len(data)), size)
+		if c.NumChunks() != len(want) {
+			t.Errorf("size %d: NumChunks() = %d, want %d", size, c.NumChunks(), len(want))
+		}
+		for _, workers := range []int{1, 3, 8} {
+			var got [][]byte
+			err := c.ReadParallel(workers, func(chunk []byte) error {
+				got = append(got, chunk)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("size %d, %d workers: ReadParallel() error: %v", size, workers, err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("size %d, %d workers: got %d chunks, want %d", size, workers, len(got), len(want))
+			}
+			for i := range got {
+				if !bytes.Equal(got[i], want[i]) {
+					t.Fatalf("size %d, %d workers: chunk %d differs from DumbChunker", size, workers, i)
+				}
+			}
+		}
+	}
+}
+
+func TestParallelDumbChunkerErrors(t *testing.T) {
+	data := makeAudio(10000, 0x21)
+
+	// The input is shorter than the size given
+	c := NewParallelDumbChunker(bytes.NewReader(data), 20000, 1024)
+	err := c.ReadParallel(4, func([]byte) error { return nil })
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadParallel() of a short input error = %v, want io.ErrUnexpectedEOF", err)
+	}
+	if _, err := c.ChunkAt(20); err == nil {
+		t.Error("ChunkAt() of an index out of range succeeded")
+	}
+
+	// An error of out stops the workers
+	errOut := errors.New("out failed")
+	c = NewParallelDumbChunker(bytes.NewReader(data), int64(len(data)), 1024)
+	n := 0
+	err = c.ReadParallel(4, func([]byte) error {
+		if n++; n == 3 {
+			return errOut
+		}
+		return nil
+	})
+	if err != errOut || n != 3 {
+		t.Errorf("ReadParallel() = %v after %d chunks, want %v after 3", err, n, errOut)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import "io"
+
+// PCMChunker wraps raw interleaved samples, such as the output of a
+// decoder or a capture device, into complete WAV files. Chunks hold whole
+// sample frames of the block align of the format and carry the canonical
+// 44-byte header, so they can be decoded in isolation.
+type PCMChunker struct {
+	*WAVChunker
+}
+
+// NewPCMChunker returns a new PCMChunker that reads samples of the given
+// format from r until EOF. Only AudioFormat, Channels, SampleRate and
+// BitsPerSample are used; a zero AudioFormat stands for PCM and ByteRate
+// is derived from the other fields.
+func NewPCMChunker(r io.Reader, chunkSize int, format WAVFormat, opts ...Option) *PCMChunker {
+	f := WAVFormat{
+		AudioFormat:   format.AudioFormat,
+		Channels:      format.Channels,
+		SampleRate:    format.SampleRate,
+		BitsPerSample: format.BitsPerSample,
+	}
+	if f.AudioFormat == 0 {
+		f.AudioFormat = wavFormatPCM
+	}
+	return &PCMChunker{newWAVFromFormat(r, f, chunkSize, opts)}
+}
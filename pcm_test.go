@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func TestPCMChunker(t *testing.T) {
+	// A ramp of 16-bit stereo samples, the right channel inverted
+	var pcm []byte
+	for i := 0; i < 3000; i++ {
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(i*7))
+		pcm = binary.LittleEndian.AppendUint16(pcm, uint16(-i*7))
+	}
+	// The byte rate is derived rather than taken from the format
+	format := WAVFormat{Channels: 2, SampleRate: 44100, BitsPerSample: 16, ByteRate: 1234}
+	want := WAVFormat{AudioFormat: 1, Channels: 2, SampleRate: 44100, BitsPerSample: 16, ByteRate: 44100 * 4}
+
+	// The room left after the header is not a multiple of the block align
+	chunks := readAllChunks(t, NewPCMChunker(bytes.NewReader(pcm), 44+1000+3, format))
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+
+	var audio []byte
+	for i, chunk := range chunks {
+		c := NewWAVChunker(bytes.NewReader(chunk), WithWAVMode(WAVModeHeaderless))
+		got, err := c.Format()
+		if err == nil {
+			t.Fatalf("chunk %d: Format() succeeded before the header was read", i)
+		}
+		data := readAllChunks(t, c)
+		if got, err = c.Format(); err != nil || got != want {
+			t.Fatalf("chunk %d: Format() = %+v, %v, want %+v", i, got, err, want)
+		}
+		var samples []byte
+		for _, d := range data {
+			samples = append(samples, d...)
+		}
+		if len(samples)%4 != 0 {
+			t.Errorf("chunk %d: %d audio bytes are not whole frames", i, len(samples))
+		}
+		if i < len(chunks)-1 && len(samples) != 1000 {
+			t.Errorf("chunk %d: got %d audio bytes, want 1000", i, len(samples))
+		}
+		audio = append(audio, samples...)
+	}
+	if !bytes.Equal(audio, pcm) {
+		t.Fatalf("decoded %d bytes that differ from the %d input bytes", len(audio), len(pcm))
+	}
+}
+
+func TestPCMChunkerInvalidChunkSize(t *testing.T) {
+	format := WAVFormat{Channels: 2, SampleRate: 44100, BitsPerSample: 16}
+	for _, size := range []int{0, MinChunkSize - 1} {
+		c := NewPCMChunker(bytes.NewReader(makeAudio(64<<10, 0x11)), size, format)
+		if _, err := c.Next(); !errors.Is(err, ErrInvalidChunkSize) {
+			t.Errorf("chunk size %d: Next() error %v, want ErrInvalidChunkSize", size, err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package main
+
+// deinterleave rearranges the interleaved sample frames in src into planar
+// order in dst: all samples of channel 0, then all samples of channel 1,
+// and so on. len(src) must be a multiple of channels*sampleSize.
+func deinterleave(dst, src []byte, channels, sampleSize int) {
+	frameSize := channels * sampleSize
+	frames := len(src) / frameSize
+	for ch := 0; ch < channels; ch++ {
+		plane := dst[ch*frames*sampleSize:]
+		for i := 0; i < frames; i++ {
+			copy(plane[i*sampleSize:(i+1)*sampleSize], src[i*frameSize+ch*sampleSize:])
+		}
+	}
+}
+
+// planar reports whether the chunker emits de-interleaved audio.
+// Planar output is only produced in headerless mode for a known format,
+// since it does not form valid WAV data.
+func (c *WAVChunker) planar() bool {
+	return c.planarOutput && c.mode == WAVModeHeaderless && c.hasFormat && c.format.BlockAlign() > 0
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math"
+)
+
+// ErrFormatMismatch is returned by a continuous WAVPlaylistChunker when
+// the files do not share the same fmt chunk.
+var ErrFormatMismatch = errors.New("wav playlist files have different formats")
+
+// WAVPlaylistChunker yields WAV chunks from a sequence of WAV files.
+// By default every file is chunked on its own, so no chunk spans two files.
+// With WithContinuous the audio of all files is chunked as one stream and
+// every chunk is wrapped with the header of the first file.
+type WAVPlaylistChunker struct {
+	readers    []io.Reader
+	targetSize int
+	continuous bool
+	opts       []Option
+	err        error
+	cur        *WAVChunker // chunker of the file being read
+	first      *WAVChunker // header source in continuous mode
+	left       int64       // audio bytes left in the current file
+}
+
+// NewWAVPlaylistChunker returns a new WAVPlaylistChunker that reads the
+// WAV files from readers in order.
+func NewWAVPlaylistChunker(readers []io.Reader, chunkSize int, opts ...Option) *WAVPlaylistChunker {
+	o := newOptions(opts)
+	return &WAVPlaylistChunker{
+		readers:    readers,
+		targetSize: chunkSize,
+		continuous: o.continuous,
+		opts:       opts,
+	}
+}
+
+// Next returns the next chunk or io.EOF when done.
+func (c *WAVPlaylistChunker) Next() ([]byte, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	var chunk []byte
+	var err error
+	if c.continuous {
+		chunk, err = c.nextContinuous()
+	} else {
+		chunk, err = c.nextFile()
+	}
+	if err != nil {
+		c.Close()
+		c.err = err
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// nextFile returns the next chunk of the current file, moving on to the
+// following file when the current one is exhausted.
+func (c *WAVPlaylistChunker) nextFile() ([]byte, error) {
+	for {
+		if c.cur == nil {
+			if len(c.readers) == 0 {
+				return nil, io.EOF
+			}
+			c.cur = NewWAVChunker(c.readers[0], c.opts...)
+			c.cur.targetSize = c.targetSize
+			c.readers = c.readers[1:]
+		}
+
+		chunk, err := c.cur.Next()
+		if err == io.EOF {
+			c.cur = nil
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return chunk, nil
+	}
+}
+
+// nextContinuous fills a chunk with audio from as many files as needed.
+func (c *WAVPlaylistChunker) nextContinuous() ([]byte, error) {
+	if c.first == nil {
+		if err := c.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	buf := c.first.audioBuffer(c.first.readSize())
+	n := 0
+	for n < len(buf) {
+		if c.left <= 0 {
+			err := c.advance()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		size := len(buf) - n
+		if int64(size) > c.left {
+			size = int(c.left)
+		}
+		m, err := io.ReadFull(c.cur.r, buf[n:n+size])
+		n += m
+		c.left -= int64(m)
+		if isErrNotEOF(err) {
+			return nil, err
+		}
+		if err != nil {
+			// Truncated file, continue with the next one
+			c.left = 0
+		}
+	}
+
+	if n == 0 {
+		return nil, io.EOF
+	}
+	return c.first.createCompleteWAVFile(buf[:n]), nil
+}
+
+// advance finishes the current file and parses the header of the next one.
+func (c *WAVPlaylistChunker) advance() error {
+	if c.cur != nil {
+		if c.cur.dataSize%2 == 1 {
+			if _, err := io.ReadFull(c.cur.r, c.cur.padding[:]); isErrNotEOF(err) {
+				return err
+			}
+		}
+		if c.cur != c.first {
+			c.cur.reset()
+		}
+		c.cur = nil
+	}
+
+	if len(c.readers) == 0 {
+		return io.EOF
+	}
+
+	cur := NewWAVChunker(c.readers[0], c.opts...)
+	cur.targetSize = c.targetSize
+	c.readers = c.readers[1:]
+
+	if err := cur.readHeader(); err != nil {
+		cur.reset()
+		return err
+	}
+
+	if c.first == nil {
+		c.first = cur
+	} else if !bytes.Equal(cur.fmtChunk(), c.first.fmtChunk()) {
+		cur.reset()
+		return ErrFormatMismatch
+	}
+
+	c.cur = cur
+	c.left = cur.dataSize
+	if cur.unbounded {
+		c.left = math.MaxInt64
+	}
+	return nil
+}
+
+// IndependentChunks reports whether every chunk can be decoded in isolation.
+// Playlist chunks are always complete WAV files.
+func (c *WAVPlaylistChunker) IndependentChunks() bool {
+	return true
+}
+
+// Close returns the buffers of the underlying chunkers to their pools.
+// Safe to call multiple times.
+func (c *WAVPlaylistChunker) Close() error {
+	if c.cur != nil {
+		c.cur.reset()
+		c.cur = nil
+	}
+	if c.first != nil {
+		c.first.reset()
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestWAVPlaylistChunkerContinuous(t *testing.T) {
+	const chunkSize = 44 + 1024
+
+	first := makeAudio(3000, 0x11)
+	second := makeAudio(5000, 0x22)
+
+	chunker := NewWAVPlaylistChunker([]io.Reader{
+		bytes.NewReader(makeWAV(1, 8000, 16, first)),
+		bytes.NewReader(makeWAV(1, 8000, 16, second)),
+	}, chunkSize, WithContinuous())
+	defer chunker.Close()
+
+	var chunks [][]byte
+	var audio []byte
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		if got := readUint32LE(chunk[40:44]); int(got) != len(chunk)-44 {
+			t.Fatalf("chunk %d: data size %d, want %d", len(chunks), got, len(chunk)-44)
+		}
+		chunks = append(chunks, chunk)
+		audio = append(audio, chunk[44:]...)
+	}
+
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if len(chunk) != chunkSize {
+			t.Errorf("chunk %d: got %d bytes, want %d", i, len(chunk), chunkSize)
+		}
+	}
+	if want := append(first, second...); !bytes.Equal(audio, want) {
+		t.Fatalf("audio mismatch: got %d bytes, want %d bytes", len(audio), len(want))
+	}
+}
+
+func TestWAVPlaylistChunkerFormatMismatch(t *testing.T) {
+	chunker := NewWAVPlaylistChunker([]io.Reader{
+		bytes.NewReader(makeWAV(1, 8000, 16, makeAudio(100, 1))),
+		bytes.NewReader(makeWAV(2, 8000, 16, makeAudio(100, 2))),
+	}, 44+1024, WithContinuous())
+	defer chunker.Close()
+
+	var err error
+	for err == nil {
+		_, err = chunker.Next()
+	}
+	if !errors.Is(err, ErrFormatMismatch) {
+		t.Fatalf("got %v, want %v", err, ErrFormatMismatch)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// NewChunkerFunc creates a chunker reading from r.
+type NewChunkerFunc func(r io.Reader, chunkSize int, opts ...Option) Chunker
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]NewChunkerFunc{
+		"mp3": func(r io.Reader, chunkSize int, opts ...Option) Chunker {
+			return NewMP3Chunker(r, chunkSize, 2048, opts...)
+		},
+		"wav": func(r io.Reader, chunkSize int, opts ...Option) Chunker {
+			return NewWAVChunker(r, append([]Option{WithChunkSize(chunkSize)}, opts...)...)
+		},
+		"flac": func(r io.Reader, chunkSize int, opts ...Option) Chunker {
+			return NewFLACChunker(r, chunkSize, opts...)
+		},
+		"ogg": func(r io.Reader, chunkSize int, opts ...Option) Chunker {
+			return NewOggChunker(r, chunkSize, opts...)
+		},
+		"aac": func(r io.Reader, chunkSize int, opts ...Option) Chunker {
+			return NewADTSChunker(r, chunkSize, opts...)
+		},
+		"aiff": func(r io.Reader, chunkSize int, opts ...Option) Chunker {
+			return NewAIFFChunker(r, chunkSize, opts...)
+		},
+		"dumb": func(r io.Reader, chunkSize int, opts ...Option) Chunker {
+			return NewDumbChunker(r, chunkSize, opts...)
+		},
+	}
+)
+
+// RegisterChunker makes a chunker available under the given file type name,
+// replacing any chunker previously registered under that name.
+func RegisterChunker(fileType string, fn NewChunkerFunc) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(fileType)] = fn
+}
+
+// SupportedTypes returns the sorted names of all registered file types.
+func SupportedTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	types := make([]string, 0, len(registry))
+	for fileType := range registry {
+		types = append(types, fileType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// NewChunker returns a chunker for the given file type reading from r.
+// Invalid chunk sizes and options are reported before the chunker is created.
+func NewChunker(fileType string, r io.Reader, chunkSize int, opts ...Option) (Chunker, error) {
+	registryMu.RLock()
+	fn, ok := registry[strings.ToLower(fileType)]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unsupported file type: %s", fileType)
+	}
+	if err := validateChunkSize(chunkSize); err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+	return fn(r, chunkSize, opts...), nil
+}
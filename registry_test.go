@@ -0,0 +1,46 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSupportedTypes(t *testing.T) {
+	types := SupportedTypes()
+	for _, fileType := range []string{"mp3", "wav", "dumb"} {
+		if !slices.Contains(types, fileType) {
+			t.Errorf("SupportedTypes() = %v, missing %q", types, fileType)
+		}
+	}
+
+	RegisterChunker("Custom", func(r io.Reader, chunkSize int, opts ...Option) Chunker {
+		return NewDumbChunker(r, chunkSize, opts...)
+	})
+	defer func() {
+		registryMu.Lock()
+		delete(registry, "custom")
+		registryMu.Unlock()
+	}()
+
+	if types := SupportedTypes(); !slices.Contains(types, "custom") {
+		t.Errorf("SupportedTypes() = %v, missing registered %q", types, "custom")
+	}
+	if _, err := NewChunker("custom", nil, 1024); err != nil {
+		t.Errorf("NewChunker() error: %v", err)
+	}
+}
+
+func TestNewChunkerInvalidGzipLevel(t *testing.T) {
+	for _, fileType := range []string{"mp3", "wav", "dumb"} {
+		_, err := NewChunker(fileType, nil, 8192, WithGzipLevel(42))
+		if err == nil || !strings.Contains(err.Error(), "invalid gzip compression level 42") {
+			t.Errorf("%s: got error %v, want invalid gzip compression level", fileType, err)
+		}
+	}
+	if _, err := NewChunker("wav", nil, 8192, WithGzipLevel(gzip.HuffmanOnly)); err != nil {
+		t.Errorf("HuffmanOnly: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+// frameSpan describes a frame appended to a chunk.
+type frameSpan struct {
+	size     int // whole frame length
+	overhead int // header, CRC and side information
+}
+
+// frameOverhead returns the number of bytes preceding the main data of a
+// Layer III frame: the header, the optional CRC and the side information.
+func frameOverhead(hdr []byte) int {
+	n := 4 + sideInfoSize(hdr)
+	if hdr[1]&0x01 == 0 {
+		n += 2
+	}
+	return n
+}
+
+// mainDataBegin returns the main_data_begin field of a Layer III frame,
+// the number of main data bytes the frame borrows from preceding frames.
+// Frames of the other layers are self-contained and borrow nothing.
+func mainDataBegin(frame []byte) int {
+	if !isLayerIII(frame) {
+		return 0
+	}
+	off := 4
+	if frame[1]&0x01 == 0 {
+		off += 2
+	}
+	if len(frame) < off+2 {
+		return 0
+	}
+	if (frame[1]>>3)&0x03 == mpeg1 {
+		return int(frame[off])<<1 | int(frame[off+1]>>7)
+	}
+	return int(frame[off])
+}
+
+// reservoirNeeded returns how many trailing bytes of the frames in spans
+// hold the last mdb bytes of main data. ok is false when spans do not
+// cover that much main data.
+func reservoirNeeded(mdb int, spans []frameSpan) (n int, ok bool) {
+	for i := len(spans) - 1; i >= 0 && mdb > 0; i-- {
+		main := spans[i].size - spans[i].overhead
+		if mdb <= main {
+			return n + mdb, true
+		}
+		n += spans[i].size
+		mdb -= main
+	}
+	return n, mdb <= 0
+}
+
+// carryReservoir appends to chunk the tail of the candidate reservoir that
+// the given first frame of the chunk references. spans describes the frames
+// of the previous chunk; the whole reservoir is carried when they do not
+// cover the referenced main data.
+func (c *MP3Chunker) carryReservoir(chunk, first []byte, spans []frameSpan) []byte {
+	n, ok := reservoirNeeded(mainDataBegin(first), spans)
+	if !ok || n > len(c.reservoir) {
+		n = len(c.reservoir)
+	}
+	c.actualReservoir = n
+	return append(chunk, c.reservoir[len(c.reservoir)-n:]...)
+}
+
+// ActualReservoir returns the number of reservoir bytes carried over at
+// the start of the chunk last returned by Next. It never exceeds the
+// reservoir size, and is smaller when the first frame of the chunk
+// references less main data from the previous frames. The bytes repeat the
+// end of the previous chunk: they are decoding context for the first frame
+// only, and are dropped when joining the chunks for playback so that no
+// frame is played twice.
+func (c *MP3Chunker) ActualReservoir() int {
+	return c.actualReservoir
+}
+
+// ReservoirLen is an alias of ActualReservoir, named for its use as the
+// length of the leading overlap to drop from the chunk.
+func (c *MP3Chunker) ReservoirLen() int {
+	return c.ActualReservoir()
+}
@@ -0,0 +1,40 @@
+package main
+
+import "time"
+
+// Result bundles a chunk with the progress of chunking.
+type Result struct {
+	Data       []byte
+	Index      int           // zero-based index of the chunk
+	BytesDone  int64         // input bytes consumed so far
+	BytesTotal int64         // expected input size, 0 if unknown
+	Last       bool          // no more chunks follow
+	Duration   time.Duration // playback duration of the audio in the chunk
+}
+
+// NextResult returns the next chunk along with progress information
+// or io.EOF when done. Last may be false for the final chunk when the
+// size of the audio data is not known up front.
+func (c *WAVChunker) NextResult() (Result, error) {
+	data, err := c.Next()
+	if err != nil {
+		return Result{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res := Result{
+		Data:      data,
+		Index:     c.chunkIndex - 1,
+		BytesDone: c.bytesRead,
+		Last:      c.err != nil || c.exhausted(),
+	}
+	if !c.unbounded {
+		res.BytesTotal = c.dataStart + c.dataSize
+		res.Last = res.Last || c.bytesRead-c.dataStart >= c.dataSize
+	}
+	if c.hasFormat {
+		res.Duration = time.Duration(c.format.Duration(c.lastAudioLen) * float64(time.Second))
+	}
+	return res, nil
+}
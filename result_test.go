@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWAVChunkerNextResult(t *testing.T) {
+	// 8 kHz 16-bit mono, 16000 bytes per second
+	data := makeAudio(40000, 0x4f)
+	wav := makeWAV(1, 8000, 16, data)
+	chunker := NewWAVChunker(bytes.NewReader(wav))
+	chunker.targetSize = 44 + 16000
+
+	want := []Result{
+		{Index: 0, BytesDone: 44 + 16000, Duration: time.Second},
+		{Index: 1, BytesDone: 44 + 32000, Duration: time.Second},
+		{Index: 2, BytesDone: 44 + 40000, Duration: time.Second / 2, Last: true},
+	}
+
+	var audio []byte
+	for i := 0; ; i++ {
+		res, err := chunker.NextResult()
+		if err == io.EOF {
+			if i != len(want) {
+				t.Fatalf("got %d results, want %d", i, len(want))
+			}
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextResult() error: %v", err)
+		}
+		if i >= len(want) {
+			t.Fatalf("got more than %d results", len(want))
+		}
+		w := want[i]
+		if res.Index != w.Index || res.BytesDone != w.BytesDone || res.BytesTotal != int64(len(wav)) ||
+			res.Last != w.Last || res.Duration != w.Duration {
+			t.Errorf("result %d = {Index:%d BytesDone:%d BytesTotal:%d Last:%v Duration:%v}, want {Index:%d BytesDone:%d BytesTotal:%d Last:%v Duration:%v}",
+				i, res.Index, res.BytesDone, res.BytesTotal, res.Last, res.Duration,
+				w.Index, w.BytesDone, len(wav), w.Last, w.Duration)
+		}
+		audio = append(audio, res.Data[44:]...)
+	}
+	if !bytes.Equal(audio, data) {
+		t.Fatalf("got %d audio bytes, want %d", len(audio), len(data))
+	}
+}
+
+func TestWAVChunkerNextResultMaxChunks(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(40000, 0x4f))
+	chunker := NewWAVChunker(bytes.NewReader(wav), WithChunkSize(44+16000), WithMaxChunks(2))
+	for i := 0; i < 2; i++ {
+		res, err := chunker.NextResult()
+		if err != nil {
+			t.Fatalf("NextResult() error: %v", err)
+		}
+		if res.Index != i || res.Last != (i == 1) {
+			t.Errorf("result %d = {Index:%d Last:%v}, want {Index:%d Last:%v}", i, res.Index, res.Last, i, i == 1)
+		}
+	}
+	if _, err := chunker.NextResult(); err != io.EOF {
+		t.Fatalf("NextResult() at the limit error: %v, want io.EOF", err)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"io"
+	"time"
+)
+
+// RetryReader wraps a reader and retries reads that fail with a retryable error.
+// If the underlying reader is an io.Seeker, it is repositioned to the last
+// known good offset before every retry; otherwise a read is retried only
+// when it did not consume any bytes.
+type RetryReader struct {
+	r          io.Reader
+	maxRetries int
+	backoff    time.Duration
+	retryable  func(error) bool
+	pos        int64 // offset just past the last byte returned
+}
+
+// NewRetryReader returns a new RetryReader that reads from r, retrying up to
+// maxRetries times with backoff between attempts for errors accepted by retryable.
+func NewRetryReader(r io.Reader, maxRetries int, backoff time.Duration, retryable func(error) bool) *RetryReader {
+	return &RetryReader{
+		r:          r,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+		retryable:  retryable,
+	}
+}
+
+// Read implements io.Reader.
+func (r *RetryReader) Read(p []byte) (int, error) {
+	for attempt := 0; ; attempt++ {
+		n, err := r.r.Read(p)
+		r.pos += int64(n)
+		if err == nil || err == io.EOF || !r.retryable(err) {
+			return n, err
+		}
+		if n > 0 {
+			// Hand out what we got, the next Read retries
+			return n, nil
+		}
+		if attempt >= r.maxRetries {
+			return 0, err
+		}
+
+		time.Sleep(r.backoff)
+
+		if s, ok := r.r.(io.Seeker); ok {
+			if _, err := s.Seek(r.pos, io.SeekStart); err != nil {
+				return 0, err
+			}
+		}
+	}
+}
+
+// WithRetry wraps the input reader of a chunker in a RetryReader.
+func WithRetry(maxRetries int, backoff time.Duration, retryable func(error) bool) Option {
+	return func(o *options) {
+		o.wrappers = append(o.wrappers, func(r io.Reader) io.Reader {
+			return NewRetryReader(r, maxRetries, backoff, retryable)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+var errTransient = errors.New("transient error")
+
+// flakyReader fails the first failures reads with errTransient.
+type flakyReader struct {
+	r        io.Reader
+	failures int
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if f.failures > 0 {
+		f.failures--
+		return 0, errTransient
+	}
+	return f.r.Read(p)
+}
+
+func TestWithRetry(t *testing.T) {
+	data := makeAudio(10000, 0x5a)
+	retryable := func(err error) bool { return errors.Is(err, errTransient) }
+
+	r := &flakyReader{r: bytes.NewReader(data), failures: 2}
+	chunker := NewDumbChunker(r, 1024, WithRetry(3, 0, retryable))
+
+	var got []byte
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		got = append(got, chunk...)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("got %d bytes, want %d bytes", len(got), len(data))
+	}
+}
+
+func TestWithRetryExhausted(t *testing.T) {
+	retryable := func(err error) bool { return errors.Is(err, errTransient) }
+
+	r := &flakyReader{r: bytes.NewReader(makeAudio(100, 0)), failures: 5}
+	chunker := NewDumbChunker(r, 1024, WithRetry(3, 0, retryable))
+
+	if _, err := chunker.Next(); !errors.Is(err, errTransient) {
+		t.Fatalf("got %v, want %v", err, errTransient)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import "encoding/binary"
+
+// ds64Chunk holds the 64-bit sizes of an RF64 file, which replace the
+// 32-bit RIFF and data sizes set to placeholders.
+type ds64Chunk struct {
+	riffSize int64
+	dataSize int64
+}
+
+// ds64MinSize is the size of the ds64 chunk without the table of sizes
+// of other chunks.
+const ds64MinSize = 28
+
+// parseDS64Chunk decodes a ds64 chunk, or returns nil if it is too short.
+// The sample count and the table of sizes of other chunks are ignored.
+func parseDS64Chunk(data []byte) *ds64Chunk {
+	if len(data) < ds64MinSize {
+		return nil
+	}
+	return &ds64Chunk{
+		riffSize: int64(binary.LittleEndian.Uint64(data[0:8])),
+		dataSize: int64(binary.LittleEndian.Uint64(data[8:16])),
+	}
+}
+
+// downgradeRF64 turns the parsed RF64 header into a plain RIFF header for
+// the chunks, which each stay far below 4 GB: the magic becomes RIFF and
+// the ds64 chunk at offset at becomes a JUNK chunk of the same size, so
+// the offsets within the header do not change.
+func (c *WAVChunker) downgradeRF64(at int) {
+	copy(c.header[0:4], "RIFF")
+	copy(c.header[at:at+4], "JUNK")
+}
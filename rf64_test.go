@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// makeRF64 converts a WAV file made by makeWAV into an RF64 file whose
+// ds64 chunk declares dataSize bytes of audio.
+func makeRF64(wav []byte, dataSize int64) []byte {
+	rf64 := insertChunk(wav, "ds64", make([]byte, ds64MinSize))
+	const ds64 = 36 + 8 // payload offset
+	binary.LittleEndian.PutUint64(rf64[ds64:], uint64(len(rf64)-8))
+	binary.LittleEndian.PutUint64(rf64[ds64+8:], uint64(dataSize))
+
+	copy(rf64[0:4], "RF64")
+	copy(rf64[4:8], writeUint32LE(placeholderSize))
+	copy(rf64[ds64+ds64MinSize+4:], writeUint32LE(placeholderSize)) // data size
+	return rf64
+}
+
+func TestWAVChunkerRF64(t *testing.T) {
+	data := makeAudio(20000, 0x64)
+	wav := makeWAV(2, 48000, 16, data)
+
+	// Bytes past the declared data size are not audio
+	rf64 := makeRF64(append(wav, makeAudio(1000, 0x99)...), int64(len(data)))
+	chunker := NewWAVChunker(bytes.NewReader(rf64), WithStrict())
+	chunker.targetSize = 4096
+
+	var audio []byte
+	for i, chunk := range readAllChunks(t, chunker) {
+		if !compareID(chunk[0:4], "RIFF") || bytes.Contains(chunk, []byte("ds64")) {
+			t.Fatalf("chunk %d is not downgraded to RIFF", i)
+		}
+		sub := NewWAVChunker(bytes.NewReader(chunk), WithStrict(), WithWAVMode(WAVModeHeaderless))
+		payload := readAllChunks(t, sub)
+		if len(payload) != 1 {
+			t.Fatalf("chunk %d is not a valid WAV file", i)
+		}
+		audio = append(audio, payload[0]...)
+	}
+	if !bytes.Equal(audio, data) {
+		t.Errorf("got %d bytes of audio, want the %d bytes declared by ds64", len(audio), len(data))
+	}
+
+	// The ds64 chunk must precede the data chunk
+	noDS64 := append([]byte(nil), wav...)
+	copy(noDS64[0:4], "RF64")
+	if _, err := NewWAVChunker(bytes.NewReader(noDS64)).Next(); err == nil {
+		t.Error("RF64 file without ds64 chunk accepted")
+	}
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// MismatchError is returned by RoundTrip when the reassembled chunks
+// differ from the input.
+type MismatchError struct {
+	Offset int64 // offset of the first differing byte
+}
+
+func (e *MismatchError) Error() string {
+	return fmt.Sprintf("reassembled chunks differ from input at offset %d", e.Offset)
+}
+
+// RoundTrip chunks the input of the given file type, reassembles the chunks
+// and compares the result byte-for-byte with the input.
+//
+// For WAV files the reassembled file is the header of the first chunk
+// followed by the audio of all chunks. The RIFF and data size fields are
+// rewritten by the chunker and are not compared, neither is anything
+// following the data chunk.
+func RoundTrip(r io.ReaderAt, size int64, fileType string) error {
+	input, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return err
+	}
+
+	var got []byte
+	var masked []int64 // offsets of 4-byte fields excluded from comparison
+	want := input
+
+	switch strings.ToLower(fileType) {
+	case "dumb":
+		got, err = reassembleDumb(io.NewSectionReader(r, 0, size))
+	case "mp3":
+		got, err = reassembleMP3(io.NewSectionReader(r, 0, size))
+	case "wav":
+		var dataOffset int
+		got, dataOffset, err = reassembleWAV(io.NewSectionReader(r, 0, size))
+		if err == nil {
+			masked = []int64{4, int64(dataOffset - 4)}
+			want = input[:wavDataEnd(input, dataOffset)]
+		}
+	default:
+		return fmt.Errorf("unsupported file type: %s", fileType)
+	}
+	if err != nil {
+		return err
+	}
+	return compareMasked(got, want, masked)
+}
+
+// VerifyMP3Lossless chunks the MP3 stream carrying over the bit reservoir,
+// concatenates the chunks without the leading reservoir bytes reported by
+// ReservoirLen and checks that the result equals the input byte-for-byte,
+// including a leading ID3v2 and a trailing ID3v1 tag. Other bytes that are
+// not part of any frame are reported as a mismatch since the chunker drops
+// them.
+func VerifyMP3Lossless(r io.ReaderAt, size int64, chunkSize int) error {
+	input, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return err
+	}
+
+	c := NewMP3Chunker(io.NewSectionReader(r, 0, size), chunkSize, maxReservoir, WithPrependTags())
+	var got []byte
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		got = append(got, chunk[c.ReservoirLen():]...)
+	}
+	got = append(got, c.TrailingTag()...)
+	return compareMasked(got, input, nil)
+}
+
+// compareMasked compares got with want, skipping the masked fields, and
+// returns a MismatchError at the first difference.
+func compareMasked(got, want []byte, masked []int64) error {
+	n := min(len(got), len(want))
+	for i := 0; i < n; i++ {
+		if got[i] != want[i] && !isMasked(masked, int64(i)) {
+			return &MismatchError{Offset: int64(i)}
+		}
+	}
+	if len(got) != len(want) {
+		return &MismatchError{Offset: int64(n)}
+	}
+	return nil
+}
+
+// isMasked reports whether off falls into one of the 4-byte masked fields.
+func isMasked(masked []int64, off int64) bool {
+	for _, m := range masked {
+		if off >= m && off < m+4 {
+			return true
+		}
+	}
+	return false
+}
+
+// wavDataEnd returns the end of the data region of the WAV file in input,
+// clamped to the input length.
+func wavDataEnd(input []byte, dataOffset int) int {
+	if dataOffset > len(input) {
+		return len(input)
+	}
+	end := int64(dataOffset) + int64(readUint32LE(input[dataOffset-4:dataOffset]))
+	if end > int64(len(input)) {
+		return len(input)
+	}
+	return int(end)
+}
+
+// reassembleDumb concatenates the chunks of a DumbChunker.
+func reassembleDumb(r io.Reader) ([]byte, error) {
+	c := NewDumbChunker(r, defaultChunkSize)
+	var out []byte
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+}
+
+// reassembleMP3 concatenates the chunks of an MP3Chunker, dropping the
+// bit reservoir carried over from the previous chunk.
+func reassembleMP3(r io.Reader) ([]byte, error) {
+	c := NewMP3Chunker(r, defaultChunkSize, maxReservoir)
+	var out []byte
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk[c.ReservoirLen():]...)
+	}
+}
+
+// reassembleWAV joins complete-mode WAV chunks into a single WAV file and
+// returns it along with the offset of its audio data.
+func reassembleWAV(r io.Reader) ([]byte, int, error) {
+	c := NewWAVChunker(r)
+	defer c.Close()
+	return joinWAVChunks(c)
+}
+
+// joinWAVChunks joins the complete WAV files returned by c into a single
+// WAV file, keeping the header of the first one, and returns it along with
+// the offset of its audio data.
+func joinWAVChunks(c Chunker) ([]byte, int, error) {
+	var out []byte
+	var dataOffset int
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		offset, err := wavDataOffset(chunk)
+		if err != nil {
+			return nil, 0, err
+		}
+		if out == nil {
+			out = append(out, chunk[:offset]...)
+			dataOffset = offset
+		}
+		out = append(out, chunk[offset:wavDataEnd(chunk, offset)]...)
+	}
+	if out == nil {
+		return nil, 0, errors.New("no wav chunks")
+	}
+
+	copy(out[4:8], writeUint32LE(uint32(len(out)-8)))
+	copy(out[dataOffset-4:dataOffset], writeUint32LE(uint32(len(out)-dataOffset)))
+	return out, dataOffset, nil
+}
+
+// wavDataOffset returns the offset of the audio data within a WAV file.
+func wavDataOffset(wav []byte) (int, error) {
+	if len(wav) < 12 || !compareID(wav[0:4], "RIFF") || !compareID(wav[8:12], "WAVE") {
+		return 0, errors.New("not a valid WAV file")
+	}
+	for off := 12; off+8 <= len(wav); {
+		size := int(readUint32LE(wav[off+4 : off+8]))
+		if compareID(wav[off:off+4], "data") {
+			return off + 8, nil
+		}
+		off += 8 + size + size%2
+	}
+	return 0, errors.New("not a valid WAV file: missing data chunk")
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		file     string
+		fileType string
+	}{
+		{"sample.wav", "wav"},
+		{"sample.mp3", "mp3"},
+		{"sample.mp3", "dumb"},
+	}
+
+	for _, tt := range tests {
+		file, err := os.Open(tt.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fi, err := file.Stat()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := RoundTrip(file, fi.Size(), tt.fileType); err != nil {
+			t.Errorf("RoundTrip(%s, %s) error: %v", tt.file, tt.fileType, err)
+		}
+		file.Close()
+	}
+}
+
+func TestVerifyMP3Lossless(t *testing.T) {
+	file, err := os.Open("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	fi, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, chunkSize := range []int{MinChunkSize, 4096, 8192, 1 << 20} {
+		if err := VerifyMP3Lossless(file, fi.Size(), chunkSize); err != nil {
+			t.Errorf("chunk size %d: %v", chunkSize, err)
+		}
+	}
+
+	// A tagged stream keeps its tag
+	tagged := append(makeID3v2([]byte("TIT2\x00\x00\x00\x01\x00\x00\x00")), makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, 10)...)
+	if err := VerifyMP3Lossless(bytes.NewReader(tagged), int64(len(tagged)), 1024); err != nil {
+		t.Errorf("tagged stream: %v", err)
+	}
+
+	// Frames referencing main data of the preceding ones carry a
+	// reservoir, which must not be duplicated in the reassembled stream
+	hdr := []byte{0xff, 0xfb, 0x90, 0x00}
+	frames := makeFrames(t, hdr, 30)
+	size, _ := frameLength(hdr)
+	for i := 1; i < 30; i++ {
+		v := i * 97 % 500
+		frames[i*size+4], frames[i*size+5] = byte(v>>1), byte(v<<7)
+	}
+	var total int
+	c := NewMP3Chunker(bytes.NewReader(frames), 1024, maxReservoir)
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += len(chunk)
+	}
+	if total <= len(frames) {
+		t.Fatalf("got %d bytes of chunks for %d bytes of frames, want a carried reservoir", total, len(frames))
+	}
+	if err := VerifyMP3Lossless(bytes.NewReader(frames), int64(len(frames)), 1024); err != nil {
+		t.Errorf("reservoir: %v", err)
+	}
+
+	// Junk between frames is dropped by the chunker
+	junk := append(makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, 2), "junk"...)
+	var mismatch *MismatchError
+	if err := VerifyMP3Lossless(bytes.NewReader(junk), int64(len(junk)), 1024); !errors.As(err, &mismatch) {
+		t.Errorf("junk: got error %v, want MismatchError", err)
+	}
+}
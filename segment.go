@@ -0,0 +1,81 @@
+package main
+
+import "time"
+
+// Layer III side information sizes in bits: the granule information of
+// a single channel in MPEG-1 and MPEG-2/2.5 streams, and the offset of
+// global_gain within it, past part2_3_length and big_values.
+const (
+	granuleBitsV1 = 59
+	granuleBitsV2 = 63
+	gainOffset    = 21
+)
+
+// bitsAt returns the n bits of b starting at bit offset off, most
+// significant bit first.
+func bitsAt(b []byte, off, n int) int {
+	v := 0
+	for i := off; i < off+n; i++ {
+		v = v<<1 | int(b[i/8]>>(7-i%8)&1)
+	}
+	return v
+}
+
+// maxGlobalGain returns the largest global_gain of the granules and
+// channels of a Layer III frame, a cheap proxy for the loudness of the
+// frame that needs no decoding. ok is false for frames of the other
+// layers and frames too short to hold the side information.
+func maxGlobalGain(frame []byte) (gain int, ok bool) {
+	if len(frame) < 4 || !isLayerIII(frame) {
+		return 0, false
+	}
+	off := 4
+	if frame[1]&0x01 == 0 {
+		off += 2
+	}
+	if len(frame) < off+sideInfoSize(frame) {
+		return 0, false
+	}
+	side := frame[off:]
+
+	channels := 2
+	if frame[3]>>6 == 3 {
+		channels = 1
+	}
+	// The granules follow main_data_begin, the private bits and, in
+	// MPEG-1, the scale factor selection information
+	granules, size, pos := 1, granuleBitsV2, 8+channels
+	if (frame[1]>>3)&0x03 == mpeg1 {
+		granules, size, pos = 2, granuleBitsV1, 16+2*channels
+	}
+	for i := 0; i < granules*channels; i++ {
+		gain = max(gain, bitsAt(side, pos+gainOffset, 8))
+		pos += size
+	}
+	return gain, true
+}
+
+// frameDuration returns the playback duration of the frame described by
+// hdr, or 0 if the header is invalid.
+func frameDuration(hdr []byte) time.Duration {
+	p, err := frameParamsOf(hdr)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(p.samplesPerFrame) * time.Second / time.Duration(p.sampleRate)
+}
+
+// silenceBreak tracks the run of silent frames the given frame extends or
+// ends, and reports whether the chunk should end after the frame, which is
+// the case once the run reaches the minimum silence duration. Every run
+// causes a single cut, so the rest of the silence starts the next chunk.
+func (c *MP3Chunker) silenceBreak(frame []byte) bool {
+	gain, ok := maxGlobalGain(frame)
+	if !ok || gain > c.segmentGain {
+		c.silentRun = 0
+		return false
+	}
+	before := c.silentRun
+	c.silentRun += frameDuration(frame)
+	return before < c.segmentSilence && c.silentRun >= c.segmentSilence
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// ChunkInfo describes a chunk and the part of the input it was made of.
+type ChunkInfo struct {
+	Index    int        `json:"index"`
+	Type     string     `json:"type"`               // file type, e.g. "wav"
+	Offset   int64      `json:"offset"`             // input offset of the first byte
+	Length   int64      `json:"length"`             // number of input bytes
+	Format   *WAVFormat `json:"format,omitempty"`   // audio format, if known
+	Duration float64    `json:"duration,omitempty"` // seconds, if known
+}
+
+// WriteChunkSidecar writes info as JSON to the file at path, so the raw
+// chunk written next to it can be interpreted later.
+func WriteChunkSidecar(path string, info ChunkInfo) error {
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// ChunkInfo describes the last chunk returned by Next. Offset and Length
+// cover the audio data of the chunk within the input.
+func (c *WAVChunker) ChunkInfo() ChunkInfo {
+	info := ChunkInfo{
+		Index:  c.chunkIndex - 1,
+		Type:   "wav",
+		Offset: c.bytesRead - int64(len(c.carry)) - int64(c.lastAudioLen),
+		Length: int64(c.lastAudioLen),
+	}
+	if c.hasFormat {
+		format := c.format
+		info.Format = &format
+		info.Duration = format.Duration(c.lastAudioLen)
+	}
+	return info
+}
+
+// ChunkInfo describes the last chunk returned by Next, which holds the
+// input bytes it covers as they are.
+func (c *DumbChunker) ChunkInfo() ChunkInfo {
+	chunks, bytes := c.Stats()
+	return ChunkInfo{
+		Index:  chunks - 1,
+		Type:   "dumb",
+		Offset: bytes - int64(c.lastLen),
+		Length: int64(c.lastLen),
+	}
+}
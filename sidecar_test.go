@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteChunkSidecar(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(40000, 0x7e))
+	chunker := NewWAVChunker(bytes.NewReader(wav), WithWAVMode(WAVModeHeaderless))
+	chunker.targetSize = 16000
+
+	dir := t.TempDir()
+	format := WAVFormat{AudioFormat: 1, Channels: 1, SampleRate: 8000, BitsPerSample: 16, ByteRate: 16000}
+
+	for i := 0; ; i++ {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+
+		info := chunker.ChunkInfo()
+		if info.Index != i || info.Type != "wav" || !bytes.Equal(chunk, wav[info.Offset:info.Offset+info.Length]) {
+			t.Fatalf("chunk %d: ChunkInfo() = %+v does not describe the chunk", i, info)
+		}
+		if info.Format == nil || *info.Format != format {
+			t.Fatalf("chunk %d: Format = %+v, want %+v", i, info.Format, format)
+		}
+		if want := float64(len(chunk)) / 16000; info.Duration != want {
+			t.Fatalf("chunk %d: Duration = %v, want %v", i, info.Duration, want)
+		}
+
+		path := filepath.Join(dir, fmt.Sprintf("part-%05d.json", i))
+		if err := WriteChunkSidecar(path, info); err != nil {
+			t.Fatalf("WriteChunkSidecar() error: %v", err)
+		}
+		p, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got ChunkInfo
+		if err := json.Unmarshal(p, &got); err != nil {
+			t.Fatalf("sidecar %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, info) {
+			t.Errorf("sidecar %d = %+v, want %+v", i, got, info)
+		}
+	}
+}
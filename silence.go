@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// silenceBlock is the duration over which the RMS level is measured.
+const silenceBlock = 10 * time.Millisecond
+
+// silenceCut returns the offset within the full audio buffer buf at which
+// the chunk should end: the middle of the quietest block within the
+// silence window at the end of buf, or len(buf) if no block is quiet
+// enough or the format cannot be analysed.
+func (c *WAVChunker) silenceCut(buf []byte) int {
+	if !c.hasFormat || c.format.SampleFormat() == SampleFormatUnknown {
+		return len(buf)
+	}
+	frame := c.format.BlockAlign()
+	blockFrames := max(int(time.Duration(c.format.SampleRate)*silenceBlock/time.Second), 1)
+	windowFrames := int(time.Duration(c.format.SampleRate) * c.silenceWindow / time.Second)
+	block := blockFrames * frame
+	window := min(windowFrames*frame, len(buf)-frame)
+
+	cut, quietest := len(buf), c.silenceLevel
+	for end := len(buf); end-block >= len(buf)-window; end -= block {
+		if level := rms(buf[end-block:end], c.format.SampleFormat()); level <= quietest {
+			cut, quietest = end-block/2, level
+		}
+	}
+	return cut - cut%frame
+}
+
+// rms returns the RMS level of the samples in b relative to full scale.
+func rms(b []byte, f SampleFormat) float64 {
+	size := sampleSize(f)
+	n := len(b) / size
+	if n == 0 {
+		return 0
+	}
+	var sum float64
+	for i := 0; i < n; i++ {
+		v := sampleValue(b[i*size:], f)
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(n))
+}
+
+// sampleSize returns the size in bytes of a single sample.
+func sampleSize(f SampleFormat) int {
+	switch f {
+	case SampleFormatU8:
+		return 1
+	case SampleFormatS16:
+		return 2
+	case SampleFormatS24:
+		return 3
+	case SampleFormatS32, SampleFormatF32:
+		return 4
+	case SampleFormatF64:
+		return 8
+	}
+	return 1
+}
+
+// sampleValue decodes the little-endian sample at the start of b into
+// the range [-1, 1].
+func sampleValue(b []byte, f SampleFormat) float64 {
+	switch f {
+	case SampleFormatU8:
+		return (float64(b[0]) - 128) / 128
+	case SampleFormatS16:
+		return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15)
+	case SampleFormatS24:
+		return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+	case SampleFormatS32:
+		return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31)
+	case SampleFormatF32:
+		return float64(math.Float32frombits(binary.LittleEndian.Uint32(b)))
+	case SampleFormatF64:
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	}
+	return 0
+}
@@ -0,0 +1,55 @@
+package main
+
+import "io"
+
+// Sink receives the chunks pushed by Drain, e.g. to write them to files,
+// upload them or publish them to a queue.
+type Sink interface {
+	// Write receives the next chunk. The sink may retain chunk, as chunks
+	// returned by Next are not reused by the chunker, unless it was
+	// created with WithReuseBuffer.
+	Write(chunk []byte, info ChunkInfo) error
+	// Close is called once after the last chunk or the first error.
+	Close() error
+}
+
+// chunkInfoer is implemented by chunkers that describe their last chunk.
+type chunkInfoer interface {
+	ChunkInfo() ChunkInfo
+}
+
+// Drain pushes all chunks of c to sink and closes it. The info passed
+// along with a chunk comes from the ChunkInfo method of c, if it has one;
+// otherwise only the index of the chunk is set. Drain returns the first
+// error of c, sink.Write or sink.Close, and nil once c is exhausted.
+func Drain(c Chunker, sink Sink) error {
+	err := drain(c, sink)
+	if cerr := sink.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// drain writes the chunks of c to sink until c is exhausted.
+func drain(c Chunker, sink Sink) error {
+	describer, _ := c.(chunkInfoer)
+	if fc, ok := c.(*fileChunker); ok {
+		describer, _ = fc.Chunker.(chunkInfoer)
+	}
+	for i := 0; ; i++ {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		info := ChunkInfo{Index: i}
+		if describer != nil {
+			info = describer.ChunkInfo()
+		}
+		if err := sink.Write(chunk, info); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+// sliceSink collects the chunks written to it.
+type sliceSink struct {
+	chunks [][]byte
+	infos  []ChunkInfo
+	closed int
+	err    error // returned by Write once set
+}
+
+func (s *sliceSink) Write(chunk []byte, info ChunkInfo) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.chunks = append(s.chunks, chunk)
+	s.infos = append(s.infos, info)
+	return nil
+}
+
+func (s *sliceSink) Close() error {
+	s.closed++
+	return nil
+}
+
+func TestDrain(t *testing.T) {
+	data, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := readAllChunks(t, NewWAVChunker(bytes.NewReader(data)))
+
+	var sink sliceSink
+	if err := Drain(NewWAVChunker(bytes.NewReader(data)), &sink); err != nil {
+		t.Fatalf("Drain() error: %v", err)
+	}
+	if sink.closed != 1 {
+		t.Errorf("Close called %d times, want 1", sink.closed)
+	}
+	if len(sink.chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d", len(sink.chunks), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(sink.chunks[i], want[i]) {
+			t.Errorf("chunk %d differs", i)
+		}
+		if sink.infos[i].Index != i || sink.infos[i].Type != "wav" {
+			t.Errorf("chunk %d: info = %+v", i, sink.infos[i])
+		}
+	}
+
+	sink = sliceSink{}
+	if err := Drain(NewDumbChunker(bytes.NewReader(data), 4096), &sink); err != nil {
+		t.Fatalf("Drain() error: %v", err)
+	}
+	if want := (ChunkInfo{Index: 3, Type: "dumb", Offset: 3 * 4096, Length: 4096}); !bytes.Equal(bytes.Join(sink.chunks, nil), data) || sink.infos[3] != want {
+		t.Errorf("dumb chunks not drained in order")
+	}
+
+	// Chunkers without ChunkInfo get the index only
+	sink = sliceSink{}
+	if err := Drain(NewMP3Chunker(bytes.NewReader(makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, 20)), 4096, 0), &sink); err != nil {
+		t.Fatalf("Drain() error: %v", err)
+	}
+	if sink.infos[1] != (ChunkInfo{Index: 1}) {
+		t.Errorf("mp3 chunk 1: info = %+v", sink.infos[1])
+	}
+
+	// A failing sink stops draining and is still closed
+	errFull := errors.New("sink full")
+	sink = sliceSink{err: errFull}
+	if err := Drain(NewDumbChunker(bytes.NewReader(data), 4096), &sink); err != errFull {
+		t.Errorf("Drain() error = %v, want %v", err, errFull)
+	}
+	if sink.closed != 1 {
+		t.Errorf("Close called %d times after an error, want 1", sink.closed)
+	}
+}
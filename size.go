@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// maxBlockSize is the largest size accepted by ParseSize.
+const maxBlockSize = 1 << 30
+
+// ParseSize parses a byte count with an optional binary suffix, such as
+// "512", "64k" or "1M". Suffixes are case-insensitive; k, m and g stand
+// for KiB, MiB and GiB and may be followed by "b" or "ib".
+func ParseSize(s string) (int, error) {
+	str := strings.ToLower(strings.TrimSpace(s))
+	str = strings.TrimSuffix(strings.TrimSuffix(str, "b"), "i")
+
+	mult := 1
+	if n := len(str); n > 0 {
+		switch str[n-1] {
+		case 'k':
+			mult = 1 << 10
+		case 'm':
+			mult = 1 << 20
+		case 'g':
+			mult = 1 << 30
+		}
+		if mult != 1 {
+			str = str[:n-1]
+		}
+	}
+
+	n, err := strconv.Atoi(str)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	if n > maxBlockSize/mult {
+		return 0, fmt.Errorf("invalid size %q: exceeds %d bytes", s, maxBlockSize)
+	}
+	return n * mult, nil
+}
+
+// sizeFlag is a flag.Value holding a size parsed by ParseSize.
+type sizeFlag int
+
+func (f *sizeFlag) String() string {
+	return strconv.Itoa(int(*f))
+}
+
+func (f *sizeFlag) Set(s string) error {
+	n, err := ParseSize(s)
+	if err != nil {
+		return err
+	}
+	*f = sizeFlag(n)
+	return nil
+}
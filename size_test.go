@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"512", 512},
+		{"64k", 65536},
+		{"64K", 65536},
+		{"64KiB", 65536},
+		{"1M", 1048576},
+		{"1mb", 1048576},
+		{"1G", 1 << 30},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if err != nil {
+			t.Errorf("ParseSize(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "k", "abc", "1.5M", "-1", "0", "12x", "2G", "99999999999999999999"} {
+		if n, err := ParseSize(in); err == nil {
+			t.Errorf("ParseSize(%q) = %d, want error", in, n)
+		}
+	}
+}
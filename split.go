@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SplitSink is a Sink that writes every chunk to its own file in a
+// directory, named after a prefix and the chunk index zero-padded to a
+// fixed width, like part-00000.bin, so that sorting the names lexically
+// keeps the chunks in order.
+type SplitSink struct {
+	dir      string
+	prefix   string
+	ext      string // file name extension, including the dot
+	width    int
+	playlist string // name of the HLS playlist written on Close, if any
+	segments []Segment
+	sidecars bool // write the ChunkInfo of every chunk next to it
+}
+
+// NewSplitSink returns a SplitSink writing to dir, which is created if
+// missing. It fails if dir cannot be created or written to.
+func NewSplitSink(dir, prefix, ext string, width int) (*SplitSink, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	// Fail before the first chunk rather than after a part of the output
+	f, err := os.CreateTemp(dir, "."+prefix+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("output directory is not writable: %w", err)
+	}
+	f.Close()
+	os.Remove(f.Name())
+
+	return &SplitSink{dir: dir, prefix: prefix, ext: ext, width: width}, nil
+}
+
+// Path returns the path of the file holding the chunk with the given index.
+func (s *SplitSink) Path(index int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%0*d%s", s.prefix, s.width, index, s.ext))
+}
+
+// SidecarPath returns the path of the file holding the ChunkInfo of the
+// chunk with the given index, like part-00000.json.
+func (s *SplitSink) SidecarPath(index int) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%0*d.json", s.prefix, s.width, index))
+}
+
+// WithSidecars makes s write the ChunkInfo of every chunk as JSON to the
+// file at SidecarPath, see WriteChunkSidecar.
+func (s *SplitSink) WithSidecars() *SplitSink {
+	s.sidecars = true
+	return s
+}
+
+// WithHLSPlaylist makes s write an HLS media playlist of the chunk files
+// to the named file in its directory when closed. Every chunk must then
+// come with its duration, as those of a WAVChunker do.
+func (s *SplitSink) WithHLSPlaylist(name string) *SplitSink {
+	s.playlist = name
+	return s
+}
+
+// Write implements Sink.
+func (s *SplitSink) Write(chunk []byte, info ChunkInfo) error {
+	if s.playlist != "" {
+		if info.Duration <= 0 {
+			return fmt.Errorf("chunk %d: duration unknown, an HLS playlist needs it", info.Index)
+		}
+		s.segments = append(s.segments, Segment{Filename: filepath.Base(s.Path(info.Index)), Duration: info.Duration})
+	}
+	if s.sidecars {
+		if err := WriteChunkSidecar(s.SidecarPath(info.Index), info); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(s.Path(info.Index), chunk, 0o644)
+}
+
+// Close implements Sink. Every chunk file is closed once written, so only
+// the HLS playlist, if any, is written.
+func (s *SplitSink) Close() error {
+	if s.playlist == "" {
+		return nil
+	}
+	f, err := os.Create(filepath.Join(s.dir, s.playlist))
+	if err != nil {
+		return err
+	}
+	if err := WriteHLSPlaylist(f, s.segments); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitSink(t *testing.T) {
+	wav := makeWAV(2, 8000, 16, makeAudio(40000, 0x11))
+	want := readAllChunks(t, NewWAVChunker(bytes.NewReader(wav), WithChunkSize(8192)))
+
+	dir := filepath.Join(t.TempDir(), "chunks")
+	sink, err := NewSplitSink(dir, "part", ".wav", 5)
+	if err != nil {
+		t.Fatalf("NewSplitSink() error: %v", err)
+	}
+	if err := Drain(NewWAVChunker(bytes.NewReader(wav), WithChunkSize(8192)), sink); err != nil {
+		t.Fatalf("Drain() error: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %d files, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		if name := filepath.Base(sink.Path(i)); e.Name() != name {
+			t.Errorf("file %d is named %s, want %s", i, e.Name(), name)
+		}
+		got, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want[i]) {
+			t.Errorf("%s does not hold chunk %d", e.Name(), i)
+		}
+	}
+	if got := filepath.Base(sink.Path(0)); got != "part-00000.wav" {
+		t.Errorf("Path(0) = %s, want part-00000.wav", got)
+	}
+}
+
+func TestSplitSinkNotWritable(t *testing.T) {
+	// A regular file where the directory should be
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSplitSink(filepath.Join(file, "chunks"), "part", ".bin", 5); err == nil {
+		t.Error("NewSplitSink() succeeded below a regular file")
+	}
+}
+
+func TestSplitSinkHLSPlaylist(t *testing.T) {
+	// 8 kHz 16-bit mono, a second of audio per full chunk
+	wav := makeWAV(1, 8000, 16, makeAudio(40000, 0x11))
+	dir := t.TempDir()
+	sink, err := NewSplitSink(dir, "part", ".wav", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Drain(NewWAVChunker(bytes.NewReader(wav), WithChunkSize(44+16000)), sink.WithHLSPlaylist("part.m3u8")); err != nil {
+		t.Fatalf("Drain() error: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "part.m3u8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want bytes.Buffer
+	WriteHLSPlaylist(&want, []Segment{{"part-00000.wav", 1}, {"part-00001.wav", 1}, {"part-00002.wav", 0.5}})
+	if string(got) != want.String() {
+		t.Errorf("got playlist %q, want %q", got, want.String())
+	}
+
+	// Chunks of unknown duration cannot be listed
+	sink, err = NewSplitSink(t.TempDir(), "part", ".bin", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Drain(NewDumbChunker(bytes.NewReader(wav), 4096), sink.WithHLSPlaylist("part.m3u8")); err == nil {
+		t.Error("Drain() of chunks without durations succeeded")
+	}
+}
+
+func TestSplitSinkSidecars(t *testing.T) {
+	data := makeAudio(5000, 0x2b)
+	dir := t.TempDir()
+	sink, err := NewSplitSink(dir, "part", ".bin", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Drain(NewDumbChunker(bytes.NewReader(data), 4096), sink.WithSidecars()); err != nil {
+		t.Fatalf("Drain() error: %v", err)
+	}
+	want := []ChunkInfo{
+		{Index: 0, Type: "dumb", Offset: 0, Length: 4096},
+		{Index: 1, Type: "dumb", Offset: 4096, Length: 904},
+	}
+	for i, w := range want {
+		if got := filepath.Base(sink.SidecarPath(i)); got != fmt.Sprintf("part-%05d.json", i) {
+			t.Errorf("SidecarPath(%d) = %s", i, got)
+		}
+		raw, err := os.ReadFile(sink.SidecarPath(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var info ChunkInfo
+		if err := json.Unmarshal(raw, &info); err != nil {
+			t.Fatal(err)
+		}
+		if info != w {
+			t.Errorf("sidecar %d = %+v, want %+v", i, info, w)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import "sync/atomic"
+
+// chunkStats counts the chunks returned by Next of the chunker embedding
+// it. The counters may be read while Next runs in another goroutine.
+type chunkStats struct {
+	chunks    atomic.Int64
+	bytes     atomic.Int64
+	maxChunks int // when non-zero, the number of chunks to stop after
+}
+
+// exhausted reports whether the chunk limit set by WithMaxChunks was
+// reached, after which Next returns io.EOF.
+func (s *chunkStats) exhausted() bool {
+	return s.maxChunks > 0 && s.chunks.Load() >= int64(s.maxChunks)
+}
+
+// count records a chunk returned by Next along with a nil error.
+func (s *chunkStats) count(chunk []byte, err error) {
+	if err == nil {
+		s.chunks.Add(1)
+		s.bytes.Add(int64(len(chunk)))
+	}
+}
+
+// Stats returns the number of chunks returned by Next so far and their
+// total size in bytes.
+func (s *chunkStats) Stats() (chunks int, bytes int64) {
+	return int(s.chunks.Load()), s.bytes.Load()
+}
+
+// resetStats clears the counters.
+func (s *chunkStats) resetStats() {
+	s.chunks.Store(0)
+	s.bytes.Store(0)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestChunkerStats(t *testing.T) {
+	wav, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]interface {
+		Chunker
+		Stats() (int, int64)
+	}{
+		"wav":  NewWAVChunker(bytes.NewReader(wav), WithChunkSize(65536)),
+		"dumb": NewDumbChunker(bytes.NewReader(wav), 65536),
+	}
+	for name, c := range tests {
+		if chunks, n := c.Stats(); chunks != 0 || n != 0 {
+			t.Errorf("%s: Stats() before Next = %d, %d, want 0, 0", name, chunks, n)
+		}
+		var total int64
+		all := readAllChunks(t, c)
+		for _, chunk := range all {
+			total += int64(len(chunk))
+		}
+		if chunks, n := c.Stats(); chunks != len(all) || n != total {
+			t.Errorf("%s: Stats() = %d, %d, want %d, %d", name, chunks, n, len(all), total)
+		}
+	}
+}
+
+func TestMP3ChunkerStats(t *testing.T) {
+	mp3, err := os.ReadFile("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := NewMP3Chunker(bytes.NewReader(mp3), 8192, maxReservoir)
+	var total int64
+	all := readAllChunks(t, c)
+	for _, chunk := range all {
+		total += int64(len(chunk))
+	}
+	if chunks, n := c.Stats(); chunks != len(all) || n != total {
+		t.Errorf("Stats() = %d, %d, want %d, %d", chunks, n, len(all), total)
+	}
+	want := 0
+	for _, chunk := range readAllChunks(t, NewMP3Chunker(bytes.NewReader(mp3), 8192, 0)) {
+		want += countFrames(t, chunk)
+	}
+	if got := c.FramesEmitted(); got != want {
+		t.Errorf("FramesEmitted() = %d, want %d", got, want)
+	}
+
+	c.Reset(bytes.NewReader(mp3))
+	if chunks, n := c.Stats(); chunks != 0 || n != 0 || c.FramesEmitted() != 0 {
+		t.Errorf("Stats() after Reset = %d, %d, %d frames, want zeros", chunks, n, c.FramesEmitted())
+	}
+}
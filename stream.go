@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"io"
+)
+
+// StreamChunk is a chunk sent by Stream, or the error that ended the stream.
+type StreamChunk struct {
+	Data []byte
+	Err  error
+}
+
+// Stream calls c.Next in a new goroutine and sends the chunks on the
+// returned channel, which is closed once c is exhausted. An error other
+// than io.EOF is sent as the last value. The channel holds up to the
+// number of chunks set by WithBufferSize, unbuffered by default; while it
+// is full the goroutine blocks instead of reading ahead. Canceling ctx
+// stops the goroutine and closes the channel, even when it is blocked on
+// a full channel.
+func Stream(ctx context.Context, c Chunker, opts ...Option) <-chan StreamChunk {
+	o := newOptions(opts)
+	ch := make(chan StreamChunk, o.bufferSize)
+	go func() {
+		defer close(ch)
+		for ctx.Err() == nil {
+			chunk, err := c.Next()
+			if err == io.EOF {
+				return
+			}
+			select {
+			case ch <- StreamChunk{Data: chunk, Err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingChunker counts the calls to Next of the wrapped chunker.
+type countingChunker struct {
+	Chunker
+	calls atomic.Int32
+}
+
+func (c *countingChunker) Next() ([]byte, error) {
+	c.calls.Add(1)
+	return c.Chunker.Next()
+}
+
+func TestStreamBackpressure(t *testing.T) {
+	const size = 3
+	c := &countingChunker{Chunker: NewDumbChunker(bytes.NewReader(make([]byte, 100*1024)), 1024)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := Stream(ctx, c, WithBufferSize(size))
+
+	// Slow consumer: let the producer fill the channel
+	for len(ch) < size {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	// The channel is full and one more chunk waits to be sent
+	if n := c.calls.Load(); n > size+1 {
+		t.Fatalf("producer called Next %d times, want at most %d", n, size+1)
+	}
+
+	// Canceling unblocks the producer, which closes the channel
+	cancel()
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-ch:
+			if !ok {
+				if n := c.calls.Load(); n > size+1 {
+					t.Fatalf("producer called Next %d times after cancel, want at most %d", n, size+1)
+				}
+				return
+			}
+		case <-timeout:
+			t.Fatal("channel not closed after cancel")
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	input := makeAudio(10*1024+5, 0x5a)
+	var got []byte
+	for sc := range Stream(context.Background(), NewDumbChunker(bytes.NewReader(input), 1024)) {
+		if sc.Err != nil {
+			t.Fatalf("stream error: %v", sc.Err)
+		}
+		got = append(got, sc.Data...)
+	}
+	if !bytes.Equal(got, input) {
+		t.Error("streamed chunks do not reassemble the input")
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrHeaderTimeout is returned when parsing the WAV header takes longer
+// than allowed by WithHeaderTimeout.
+var ErrHeaderTimeout = errors.New("wav header parsing timed out")
+
+// deadlineReader fails reads started after the deadline.
+type deadlineReader struct {
+	r        io.Reader
+	deadline time.Time
+}
+
+func (d *deadlineReader) Read(p []byte) (int, error) {
+	if time.Now().After(d.deadline) {
+		return 0, ErrHeaderTimeout
+	}
+	return d.r.Read(p)
+}
+
+// readDeadliner is implemented by readers supporting read deadlines,
+// such as network connections and pipes.
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+// readHeader parses the WAV header within the configured header timeout.
+// Readers that support read deadlines are interrupted when it expires,
+// others are checked between reads.
+func (c *WAVChunker) readHeader() error {
+	if c.headerTimeout <= 0 {
+		return c.parseWAVHeader()
+	}
+
+	r := c.r
+	deadline := time.Now().Add(c.headerTimeout)
+	c.r = &deadlineReader{r: r, deadline: deadline}
+	defer func() { c.r = r }()
+
+	if d, ok := r.(readDeadliner); ok && d.SetReadDeadline(deadline) == nil {
+		defer d.SetReadDeadline(time.Time{})
+	}
+
+	err := c.parseWAVHeader()
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return ErrHeaderTimeout
+	}
+	return err
+}
@@ -1,18 +1,57 @@
 package main
 
 import (
+	"compress/gzip"
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"math"
 	"runtime"
 	"sync"
+	"time"
 )
 
 const defaultChunkSize = 8192
 
 // Maximum size for non-data chunks to prevent OOM attacks
-const maxChunkSize = 1024 * 1024 // 1MB should be more than enough for WAV metadata
-const maxHeaderSize = 8 << 20    // 8 MB
-const minChunkSize = 1024        // 1KB
+const maxMetadataChunkSize = 1024 * 1024 // 1MB should be more than enough for WAV metadata
+const maxHeaderSize = 8 << 20            // 8 MB
+const minChunkSize = 1024                // 1KB
+
+// defaultMaxMetadataChunks is the default limit of chunks preceding the
+// data chunk; real files have a handful.
+const defaultMaxMetadataChunks = 1024
+
+// placeholderSize is written to the RIFF and data sizes by streaming
+// writers that do not know the final length.
+const placeholderSize = 0xffffffff
+
+// ErrInconsistentRIFFSize is returned in strict mode when the RIFF size
+// is smaller than the header and data that follow it.
+var ErrInconsistentRIFFSize = errors.New("wav riff size is smaller than the header and data")
+
+// ErrTooManyChunks is returned when more chunks than allowed by
+// WithMaxMetadataChunks precede the data chunk.
+var ErrTooManyChunks = errors.New("too many wav chunks before data")
+
+// ErrTruncatedHeader is returned when the input ends within the WAV
+// header. The error names the structure being read and also matches
+// io.ErrUnexpectedEOF.
+var ErrTruncatedHeader = errors.New("wav header truncated")
+
+// truncated wraps err into ErrTruncatedHeader if the input ended while
+// reading the header structure what.
+func truncated(err error, what string) error {
+	if err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: reading %s: %w", ErrTruncatedHeader, what, err)
+	}
+	return err
+}
+
+// ErrChunkTooLarge is returned when a non-data chunk exceeds maxMetadataChunkSize.
+// The data chunk is streamed and thus not subject to this limit.
+var ErrChunkTooLarge = errors.New("chunk size too large")
 
 // Pool for reusable byte buffers with 512 capacity
 // Beneficial for concurrent operations in service environments
@@ -40,18 +79,72 @@ func compareID(data []byte, id string) bool {
 	return data[0] == id[0] && data[1] == id[1] && data[2] == id[2] && data[3] == id[3]
 }
 
+// WAVChunkMode selects how WAVChunker wraps the audio it emits.
+type WAVChunkMode int
+
+const (
+	// WAVModeComplete emits every chunk as a complete WAV file.
+	WAVModeComplete WAVChunkMode = iota
+	// WAVModeHeaderless emits only the bytes of the data region split into
+	// fixed-size chunks, without any header.
+	WAVModeHeaderless
+	// WAVModeStreaming emits the header once, in front of the audio of the
+	// first chunk, and only audio afterwards, so the chunks joined together
+	// form a single WAV stream.
+	WAVModeStreaming
+)
+
 // WAVChunker yields WAV chunks as complete WAV files.
 // WAV files are much simpler to chunk since they don't have frame dependencies.
 type WAVChunker struct {
+	canceler
+	chunkStats
 	r              io.Reader
 	targetSize     int
+	mode           WAVChunkMode
 	err            error
 	headerSent     bool
 	dataStart      int64
 	bytesRead      int64
-	dataSize       uint32
+	dataSize       int64
 	dataSizeOffset int64
+	fmtOffset      int // offset of the fmt chunk payload within header
+	fmtSize        int // size of the fmt chunk payload, 0 if absent
+	format         WAVFormat
+	hasFormat      bool
+	loops          []Loop
+	ixml           string
+	hasIXML        bool
+	axml           string
+	hasAXML        bool
+	cart           *CartInfo
+	bext           *BextInfo
 	closed         bool
+	unpooled       bool // allocate fresh buffers instead of using the pools
+	drain          bool // read the input past the data chunk until EOF
+	peeked         bool // audio of the next chunk is already in the audio buffer
+	peekedLen      int
+	ended          bool // the input ended while reading the peeked audio
+	unbounded      bool // audio data extends until the end of the input
+	planarOutput   bool // de-interleave the audio of headerless chunks
+	strict         bool // reject inconsistent headers
+	padOdd         bool // pad odd-sized data chunks to an even length
+	maxMetaChunks  int  // limit of chunks before data, 0 for none
+	timeRef        bool // stamp the chunk position into the bext chunk
+	bextOffset     int  // offset of the bext chunk payload within header
+	gzipLevel      int  // compression level of the chunks, or gzip.NoCompression
+	stripMeta      bool // leave the metadata chunks out of all but the first chunk
+	silenceWindow  time.Duration
+	silenceLevel   float64
+	carry          []byte // audio read past the last silence-aware cut
+	chunkIndex     int    // index of the next chunk, the number returned so far
+	samplePos      uint64 // sample frames in the chunks returned so far
+	lastAudioLen   int    // audio bytes in the last returned chunk
+	headerTimeout  time.Duration
+	headerPool     *sync.Pool
+	audioPool      *sync.Pool
+	metrics        Metrics
+	wavl           *wavlReader // audio source of a wave list instead of a data chunk
 	// Reusable buffers to reduce allocations
 	riff    []byte
 	chunk   []byte
@@ -60,15 +153,47 @@ type WAVChunker struct {
 	padding [1]byte
 }
 
-// NewWAVChunker returns a new WAVChunker that reads from r with fixed 8192 chunk size.
-func NewWAVChunker(r io.Reader) *WAVChunker {
+// NewWAVChunker returns a new WAVChunker that reads from r. The chunk size
+// is 8192 bytes unless set with WithChunkSize.
+func NewWAVChunker(r io.Reader, opts ...Option) *WAVChunker {
+	o := newOptions(opts)
+	targetSize := defaultChunkSize
+	if validateChunkSize(o.chunkSize) == nil {
+		targetSize = o.chunkSize
+	}
+	gzipLevel := gzip.NoCompression
+	if validGzipLevel(o.gzipLevel) {
+		gzipLevel = o.gzipLevel
+	}
 	c := &WAVChunker{
-		r:          r,
-		targetSize: defaultChunkSize,
-		riff:       make([]byte, 12),                // Reusable RIFF header buffer
-		chunk:      make([]byte, 8),                 // Reusable 8-byte buffer for chunk headers
-		header:     headerBufferPool.Get().([]byte), // Reusable header buffer
-		audio:      audioBufferPool.Get().([]byte),  // Get audio buffer from pool
+		r:             o.reader(r),
+		targetSize:    targetSize,
+		mode:          o.wavMode,
+		unpooled:      o.noPooling,
+		headerPool:    o.headerPool,
+		audioPool:     o.audioPool,
+		drain:         o.hash != nil,
+		headerTimeout: o.headerTimeout,
+		planarOutput:  o.planar,
+		strict:        o.strict,
+		padOdd:        o.padOdd,
+		maxMetaChunks: o.maxMetaChunks,
+		timeRef:       o.bextTimeRef,
+		gzipLevel:     gzipLevel,
+		stripMeta:     o.noMetadata,
+		silenceWindow: o.silenceWindow,
+		silenceLevel:  o.silenceLevel,
+		metrics:       o.metrics,
+		chunkStats:    chunkStats{maxChunks: o.maxChunks},
+		riff:          make([]byte, 12), // Reusable RIFF header buffer
+		chunk:         make([]byte, 8),  // Reusable 8-byte buffer for chunk headers
+	}
+	if c.unpooled {
+		c.header = make([]byte, 0, 512)
+		c.audio = make([]byte, targetSize)
+	} else {
+		c.header = c.headerPool.Get().([]byte) // Reusable header buffer
+		c.audio = c.audioPool.Get().([]byte)   // Get audio buffer from pool
 	}
 	// Set finalizer to ensure pool cleanup even if client abandons iteration
 	runtime.SetFinalizer(c, (*WAVChunker).Close)
@@ -91,22 +216,33 @@ func (c *WAVChunker) reset() {
 
 func (c *WAVChunker) resetAudioBuffer() {
 	if c.audio != nil {
-		audioBufferPool.Put(c.audio)
+		if !c.unpooled {
+			c.audioPool.Put(c.audio)
+		}
 		c.audio = nil
 	}
 }
 
 func (c *WAVChunker) resetHeaderBuffer() {
 	if c.header != nil {
-		headerBufferPool.Put(c.header)
+		if !c.unpooled {
+			c.headerPool.Put(c.header)
+		}
 		c.header = nil
 	}
 }
 
+// IndependentChunks reports whether every chunk can be decoded in isolation,
+// which holds only when each chunk is a complete WAV file.
+func (c *WAVChunker) IndependentChunks() bool {
+	return c.mode == WAVModeComplete
+}
+
 // Close returns the buffers back to their respective pools and clears the finalizer.
 // Safe to call multiple times.
-func (c *WAVChunker) Close() {
+func (c *WAVChunker) Close() error {
 	c.reset()
+	return nil
 }
 
 // writeUint32LE writes a 32-bit little-endian unsigned integer
@@ -137,14 +273,16 @@ func (c *WAVChunker) parseWAVHeader() error {
 	// Read RIFF header (12 bytes) - reuse buffer
 	n, err := io.ReadFull(c.r, c.riff)
 	if err != nil {
-		return err
+		return truncated(err, "RIFF header")
 	}
 	if n != 12 {
 		return errors.New("incomplete RIFF header")
 	}
 
-	// Check RIFF signature using byte comparison
-	if !compareID(c.riff[0:4], "RIFF") {
+	// Check RIFF signature using byte comparison; RF64 and BW64 files
+	// keep their sizes in a ds64 chunk
+	rf64 := compareID(c.riff[0:4], "RF64") || compareID(c.riff[0:4], "BW64")
+	if !compareID(c.riff[0:4], "RIFF") && !rf64 {
 		return errors.New("not a valid WAV file: missing RIFF signature")
 	}
 
@@ -155,15 +293,21 @@ func (c *WAVChunker) parseWAVHeader() error {
 
 	c.header = append(c.header, c.riff...)
 
+	var ds64 *ds64Chunk
+	ds64At := 0 // offset of the ds64 chunk within header
+
 	// Read chunks until we find the data chunk
-	for {
+	for chunks := 0; ; chunks++ {
 		if len(c.header) > maxHeaderSize {
 			return errors.New("wav header too large")
 		}
+		if c.maxMetaChunks > 0 && chunks > c.maxMetaChunks {
+			return ErrTooManyChunks
+		}
 		// Reuse the chunk buffer
 		n, err := io.ReadFull(c.r, c.chunk)
 		if err != nil {
-			return err
+			return truncated(err, "chunk header")
 		}
 		if n != 8 {
 			return errors.New("incomplete chunk header")
@@ -171,34 +315,81 @@ func (c *WAVChunker) parseWAVHeader() error {
 
 		// Use byte comparison instead of string conversion
 		isDataChunk := compareID(c.chunk[0:4], "data")
+		isFmtChunk := compareID(c.chunk[0:4], "fmt ")
+		isListChunk := compareID(c.chunk[0:4], "LIST")
 		chunkSize := readUint32LE(c.chunk[4:8])
 
 		c.header = append(c.header, c.chunk...)
 
 		if isDataChunk {
 			// Found the data chunk
-			c.dataSize = chunkSize
+			if rf64 {
+				if ds64 == nil {
+					return errors.New("not a valid RF64 file: missing ds64 chunk")
+				}
+				if c.strict && ds64.riffSize+8 < int64(len(c.header))+ds64.dataSize {
+					return ErrInconsistentRIFFSize
+				}
+				c.downgradeRF64(ds64At)
+				c.dataSize = ds64.dataSize
+				c.dataStart = int64(len(c.header))
+				c.dataSizeOffset = int64(len(c.header) - 4)
+				c.bytesRead = int64(len(c.header))
+				return nil
+			}
+			riffSize := readUint32LE(c.riff[4:8])
+			c.unbounded = unknownDataSize(riffSize, chunkSize)
+			if c.strict && !c.unbounded && !riffSizeConsistent(riffSize, len(c.header), chunkSize) {
+				return ErrInconsistentRIFFSize
+			}
+			c.dataSize = int64(chunkSize)
 			c.dataStart = int64(len(c.header))
 			c.dataSizeOffset = int64(len(c.header) - 4)
 			c.bytesRead = int64(len(c.header))
 			return nil
 		}
 
+		// A wave list holds the audio in place of the data chunk
+		var listType []byte
+		if isListChunk && chunkSize >= 4 {
+			listType = make([]byte, 4)
+			if _, err := io.ReadFull(c.r, listType); err != nil {
+				return truncated(noEOF(err), "chunk data")
+			}
+			if compareID(listType, "wavl") {
+				return c.startWaveList(chunkSize - 4)
+			}
+		}
+
 		// Read and include the chunk data in the header
 		// Guard against maliciously large chunk sizes that could cause OOM
-		if chunkSize > maxChunkSize {
-			return errors.New("chunk size too large")
+		if chunkSize > maxMetadataChunkSize {
+			return ErrChunkTooLarge
 		}
 
 		chunkData := make([]byte, chunkSize)
-		n, err = io.ReadFull(c.r, chunkData)
+		copy(chunkData, listType)
+		n, err = io.ReadFull(c.r, chunkData[len(listType):])
 		if err != nil {
-			return err
+			return truncated(noEOF(err), "chunk data")
 		}
-		if n != int(chunkSize) {
+		if n != int(chunkSize)-len(listType) {
 			return errors.New("incomplete chunk data")
 		}
 
+		if isFmtChunk {
+			c.fmtOffset = len(c.header)
+			c.fmtSize = int(chunkSize)
+			c.format, c.hasFormat = parseWAVFormat(chunkData)
+		} else if rf64 && compareID(c.chunk[0:4], "ds64") {
+			if ds64 = parseDS64Chunk(chunkData); ds64 == nil {
+				return errors.New("not a valid RF64 file: ds64 chunk too short")
+			}
+			ds64At = len(c.header) - 8
+		} else {
+			c.parseMetadataChunk(c.chunk[0:4], chunkData)
+		}
+
 		c.header = append(c.header, chunkData...)
 
 		// WAV chunks must be aligned on 2-byte boundaries
@@ -214,6 +405,169 @@ func (c *WAVChunker) parseWAVHeader() error {
 	}
 }
 
+// unknownDataSize reports whether the data size is a placeholder left by a
+// streaming writer that did not know the length, so the audio extends
+// until the end of the input. A zero data size only counts as such if the
+// RIFF size is a placeholder or zero too, as an empty data chunk may be
+// followed by further chunks.
+func unknownDataSize(riffSize, dataSize uint32) bool {
+	switch dataSize {
+	case placeholderSize:
+		return true
+	case 0:
+		return riffSize == 0 || riffSize == placeholderSize
+	}
+	return false
+}
+
+// riffSizeConsistent reports whether the RIFF size covers the header of
+// the given length and the data chunk. Placeholder sizes of streaming
+// writers are accepted.
+func riffSizeConsistent(riffSize uint32, headerLen int, dataSize uint32) bool {
+	if riffSize == placeholderSize {
+		return true
+	}
+	need := int64(headerLen)
+	if dataSize != placeholderSize {
+		need += int64(dataSize)
+	}
+	return int64(riffSize)+8 >= need
+}
+
+// fmtChunk returns the payload of the parsed fmt chunk or nil if there was none.
+func (c *WAVChunker) fmtChunk() []byte {
+	if c.fmtSize == 0 {
+		return nil
+	}
+	return c.header[c.fmtOffset : c.fmtOffset+c.fmtSize]
+}
+
+// Format returns the audio format parsed from the fmt chunk.
+// It is available after the first call to Next or to ReadHeader.
+func (c *WAVChunker) Format() (WAVFormat, error) {
+	if !c.hasFormat {
+		return WAVFormat{}, ErrNoFormat
+	}
+	f := c.format
+	f.Planar = c.planar()
+	return f, nil
+}
+
+// readSize returns the number of audio bytes to read for the next chunk,
+// leaving room for the header of complete-mode chunks and rounded down to
+// whole sample frames.
+func (c *WAVChunker) readSize() int {
+	if c.mode == WAVModeHeaderless && !c.planar() {
+		return c.targetSize
+	}
+	readSize := c.targetSize
+	if c.mode == WAVModeComplete {
+		readSize -= len(c.header)
+	}
+	if readSize <= 0 {
+		readSize = minChunkSize
+	}
+	if c.hasFormat {
+		if frame := c.format.BlockAlign(); frame > 1 {
+			readSize -= readSize % frame
+			if readSize == 0 {
+				readSize = frame
+			}
+		}
+	}
+	return readSize
+}
+
+// EffectiveChunkSize returns the number of audio bytes the chunker puts
+// into a chunk after all adjustments to the requested chunk size: room for
+// the header, the minimum chunk size and rounding to whole sample frames.
+// Only the last chunk and chunks cut at silences are shorter. It is
+// available after the first call to Next or to ReadHeader and 0 before.
+func (c *WAVChunker) EffectiveChunkSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.headerSent {
+		return 0
+	}
+	return c.readSize()
+}
+
+// audioBuffer returns the reusable audio buffer resized to n bytes.
+// Without pooling a fresh buffer is allocated on every call.
+func (c *WAVChunker) audioBuffer(n int) []byte {
+	if c.unpooled {
+		c.audio = make([]byte, n)
+		return c.audio
+	}
+	if len(c.audio) < n {
+		if cap(c.audio) >= n {
+			// We have enough capacity, just extend the slice
+			c.audio = c.audio[:n]
+		} else {
+			c.resetAudioBuffer()
+			// Allocate new buffer (can't use pool for sizes > defaultChunkSize)
+			c.audio = make([]byte, n)
+		}
+	}
+	return c.audio[:n]
+}
+
+// canonicalHeader returns the 44-byte RIFF/fmt/data header for the given
+// format, with the RIFF and data sizes left zeroed.
+func canonicalHeader(f WAVFormat) []byte {
+	h := make([]byte, 0, 44)
+	h = append(h, "RIFF"...)
+	h = append(h, 0, 0, 0, 0)
+	h = append(h, "WAVEfmt "...)
+	h = append(h, writeUint32LE(16)...)
+	h = append(h, byte(f.AudioFormat), byte(f.AudioFormat>>8))
+	h = append(h, byte(f.Channels), byte(f.Channels>>8))
+	h = append(h, writeUint32LE(f.SampleRate)...)
+	h = append(h, writeUint32LE(f.ByteRate)...)
+	blockAlign := f.BlockAlign()
+	h = append(h, byte(blockAlign), byte(blockAlign>>8))
+	h = append(h, byte(f.BitsPerSample), byte(f.BitsPerSample>>8))
+	h = append(h, "data"...)
+	h = append(h, 0, 0, 0, 0)
+	return h
+}
+
+// NewWAVFromPCM returns a WAVChunker that wraps raw interleaved PCM samples
+// read from r into complete WAV files of the given format. Chunks hold
+// whole sample frames and the input is read until EOF.
+func NewWAVFromPCM(r io.Reader, sampleRate, channels, bitsPerSample, chunkSize int, opts ...Option) *WAVChunker {
+	return newWAVFromFormat(r, WAVFormat{
+		AudioFormat:   wavFormatPCM,
+		Channels:      uint16(channels),
+		SampleRate:    uint32(sampleRate),
+		BitsPerSample: uint16(bitsPerSample),
+	}, chunkSize, opts)
+}
+
+// newWAVFromFormat returns a WAVChunker that reads headerless samples of
+// format f from r, as if it had parsed the canonical header of the format.
+// A zero byte rate is derived from the sample rate and block align. An
+// invalid chunk size fails the first call to Next with ErrInvalidChunkSize.
+func newWAVFromFormat(r io.Reader, f WAVFormat, chunkSize int, opts []Option) *WAVChunker {
+	c := NewWAVChunker(r, opts...)
+	if err := validateChunkSize(chunkSize); err != nil {
+		c.err = err
+	} else {
+		c.targetSize = chunkSize
+	}
+	c.format = f
+	if c.format.ByteRate == 0 {
+		c.format.ByteRate = c.format.SampleRate * uint32(c.format.BlockAlign())
+	}
+	c.hasFormat = true
+	c.header = append(c.header[:0], canonicalHeader(c.format)...)
+	c.fmtOffset, c.fmtSize = 20, 16
+	c.dataSizeOffset, c.dataStart, c.bytesRead = 40, 44, 44
+	c.headerSent = true
+	c.unbounded = true
+	return c
+}
+
 // createCompleteWAVFile creates a complete WAV file from header and audio data
 // Returns nil when audioData is empty
 func (c *WAVChunker) createCompleteWAVFile(audioData []byte) []byte {
@@ -224,6 +578,10 @@ func (c *WAVChunker) createCompleteWAVFile(audioData []byte) []byte {
 	headerLen := len(c.header)
 	audioLen := len(audioData)
 	totalLen := headerLen + audioLen
+	if c.padOdd && audioLen%2 == 1 {
+		// The data chunk keeps its odd size and is followed by a pad byte
+		totalLen++
+	}
 
 	// Allocate result buffer once
 	result := make([]byte, totalLen)
@@ -248,23 +606,204 @@ func (c *WAVChunker) createCompleteWAVFile(audioData []byte) []byte {
 
 // Next returns the next chunk or io.EOF when done.
 func (c *WAVChunker) Next() ([]byte, error) {
+	chunk, err := c.guard(c.next, c.cancelCleanup)
+	c.count(chunk, err)
+	return observe(c.metrics, chunk, err)
+}
+
+// NextContext is like Next but gives up once ctx is done, returning
+// ctx.Err(). The pooled buffers are then returned at once instead of when
+// the chunker is closed or collected, and later calls fail with the same
+// error. A chunk read while ctx expired is dropped.
+func (c *WAVChunker) NextContext(ctx context.Context) ([]byte, error) {
+	next := func() ([]byte, error) {
+		if err := ctx.Err(); err != nil {
+			c.contextCleanup(err)
+			return nil, err
+		}
+		chunk, err := c.next()
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			c.contextCleanup(ctxErr)
+			return nil, ctxErr
+		}
+		return chunk, err
+	}
+	chunk, err := c.guard(next, c.cancelCleanup)
+	c.count(chunk, err)
+	return observe(c.metrics, chunk, err)
+}
+
+// contextCleanup releases the buffers once the context of NextContext is
+// done. reset makes it safe to call after the buffers were released.
+func (c *WAVChunker) contextCleanup(err error) {
+	c.peeked = false
+	c.reset()
+	c.err = err
+}
+
+// stopAtLimit reports whether the limit set by WithMaxChunks was reached.
+// The stream then ends, returning the pooled buffers like the end of the
+// audio does. It is called with mu held.
+func (c *WAVChunker) stopAtLimit() bool {
+	if !c.exhausted() {
+		return false
+	}
+	c.peeked = false
+	c.reset()
+	if c.err == nil {
+		c.err = io.EOF
+	}
+	return true
+}
+
+// next builds the next chunk from the peeked audio.
+// It never returns an empty chunk with a nil error: once there is no
+// audio left it fails with io.EOF instead.
+func (c *WAVChunker) next() ([]byte, error) {
+	if c.stopAtLimit() {
+		return nil, io.EOF
+	}
+	audioData, err := c.peekAudio()
+	if err != nil {
+		return nil, err
+	}
+	if len(audioData) == 0 {
+		c.peeked = false
+		c.reset()
+		c.err = io.EOF
+		return nil, io.EOF
+	}
+	c.peeked = false
+	c.chunkIndex++
+	c.lastAudioLen = len(audioData)
+
+	var chunk []byte
+	if c.planar() {
+		chunk = make([]byte, len(audioData))
+		f := c.format
+		deinterleave(chunk, audioData, int(f.Channels), f.BlockAlign()/int(f.Channels))
+	} else if c.mode == WAVModeHeaderless {
+		chunk = append([]byte(nil), audioData...)
+	} else if c.mode == WAVModeStreaming {
+		// Only the first chunk carries the header, with the sizes of the input
+		if c.chunkIndex == 1 {
+			chunk = append(chunk, c.header...)
+		}
+		chunk = append(chunk, audioData...)
+	} else {
+		// Each chunk is a complete WAV file
+		chunk = c.createCompleteWAVFile(audioData)
+		if c.timeRef {
+			c.stampTimeReference(chunk)
+		}
+		if c.stripMeta && c.chunkIndex == 1 {
+			c.stripMetadata()
+		}
+	}
+	if c.hasFormat && c.format.BlockAlign() > 0 {
+		c.samplePos += uint64(len(audioData) / c.format.BlockAlign())
+	}
+
+	if c.ended {
+		// We stop processing when we hit EOF or unexpected EOF
+		// Both are treated as end of stream - return the non-empty chunk
+		// with nil error, the next call returns io.EOF
+		c.reset()
+		c.err = io.EOF
+		return c.compress(chunk), nil
+	}
+
+	return c.compress(chunk), nil
+}
+
+// NextSize returns the size of the chunk the next call to Next will return,
+// e.g. to set Content-Length before writing it. The audio of the chunk is
+// read ahead and kept until Next is called. It fails with
+// ErrCompressedSize when WithGzipLevel compresses the chunks.
+func (c *WAVChunker) NextSize() (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopAtLimit() {
+		return 0, io.EOF
+	}
+	if c.gzipLevel != gzip.NoCompression {
+		return 0, ErrCompressedSize
+	}
+	audioData, err := c.peekAudio()
+	if err != nil {
+		return 0, err
+	}
+	if c.mode == WAVModeHeaderless || len(audioData) == 0 {
+		return len(audioData), nil
+	}
+	if c.mode == WAVModeStreaming {
+		if c.chunkIndex == 0 {
+			return len(c.header) + len(audioData), nil
+		}
+		return len(audioData), nil
+	}
+	n := len(c.header) + len(audioData)
+	if c.padOdd && len(audioData)%2 == 1 {
+		n++
+	}
+	return n, nil
+}
+
+// ReadHeader parses the WAV header without reading any audio, so that
+// Format and the metadata accessors are available before the first call
+// to Next. Calling it is optional: Next parses the header if needed.
+func (c *WAVChunker) ReadHeader() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return c.startAudio()
+}
+
+// startAudio parses the header unless it was parsed already.
+func (c *WAVChunker) startAudio() error {
+	if c.headerSent {
+		return nil
+	}
+	if err := c.readHeader(); err != nil {
+		c.reset()
+		c.err = err
+		return err
+	}
+	c.headerSent = true
+	if c.timeRef && c.mode == WAVModeComplete {
+		c.addBext()
+	}
+	if c.wavl != nil {
+		// Read the audio from the wave list from now on
+		c.wavl.r = c.r
+		c.r = c.wavl
+	}
+	return nil
+}
+
+// peekAudio reads the audio data of the next chunk into the audio buffer,
+// unless it was already read by a previous call.
+func (c *WAVChunker) peekAudio() ([]byte, error) {
+	if c.peeked {
+		return c.audio[:c.peekedLen], nil
+	}
 	if c.err != nil {
 		return nil, c.err
 	}
 
 	// Parse header on first call
-	if !c.headerSent {
-		if err := c.parseWAVHeader(); err != nil {
-			c.reset()
-			c.err = err
-			return nil, err
-		}
-		c.headerSent = true
+	if err := c.startAudio(); err != nil {
+		return nil, err
 	}
 
 	// Check if we've read all the audio data
-	audioDataLeft := int64(c.dataSize) - (c.bytesRead - c.dataStart)
-	if audioDataLeft <= 0 {
+	audioDataLeft := c.dataSize - (c.bytesRead - c.dataStart)
+	if c.unbounded {
+		audioDataLeft = math.MaxInt64
+	}
+	if audioDataLeft <= 0 && len(c.carry) == 0 {
 		// If data size is odd, consume the padding byte
 		if c.dataSize%2 == 1 {
 			_, err := io.ReadFull(c.r, c.padding[:])
@@ -274,57 +813,74 @@ func (c *WAVChunker) Next() ([]byte, error) {
 				return nil, err
 			}
 		}
+		// Consume the chunks following the audio data so that
+		// a stream hash covers the whole input
+		if c.drain {
+			r := c.r
+			if c.wavl != nil {
+				r = c.wavl.r
+			}
+			if _, err := io.Copy(io.Discard, r); err != nil {
+				c.reset()
+				c.err = err
+				return nil, err
+			}
+		}
 		c.reset()
+		c.err = io.EOF
 		return nil, io.EOF
 	}
 
 	// Read audio data for this chunk
 	// Subtract header size from target to leave room for header
-	readSize := c.targetSize - len(c.header)
-	if readSize <= 0 {
-		readSize = minChunkSize
-	}
+	readSize := c.readSize()
+	buf := c.audioBuffer(readSize)
 
-	if int64(readSize) > audioDataLeft {
-		readSize = int(audioDataLeft)
-	}
+	// Start with the audio left over from the previous chunk
+	carried := copy(buf, c.carry)
+	c.carry = c.carry[:0]
+	readSize -= carried
 
-	// Resize audio buffer if needed
-	if len(c.audio) < readSize {
-		if cap(c.audio) >= readSize {
-			// We have enough capacity, just extend the slice
-			c.audio = c.audio[:readSize]
-		} else {
-			c.resetAudioBuffer()
-			// Allocate new buffer (can't use pool for sizes > defaultChunkSize)
-			c.audio = make([]byte, readSize)
-		}
+	if int64(readSize) > audioDataLeft {
+		readSize = int(max(audioDataLeft, 0))
 	}
 
 	// Read directly into the reusable buffer using ReadFull to avoid partial reads
-	n, err := io.ReadFull(c.r, c.audio[:readSize])
-	if err != nil && !errors.Is(err, io.EOF) {
+	n, err := io.ReadFull(c.r, buf[carried:carried+readSize])
+	if isErrNotEOF(err) {
 		c.reset()
 		c.err = err
 		return nil, err
 	}
 
 	c.bytesRead += int64(n)
-
-	audioData := c.audio[:n] // Slice the buffer to actual read size
-	// Each chunk is a complete WAV file
-	chunk := c.createCompleteWAVFile(audioData)
-
-	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
-		// We stop processing when we hit EOF or unexpected EOF
-		// Both are treated as end of stream - return chunk with nil error
+	n += carried
+	if n == 0 {
+		// The input ended right at a chunk boundary
 		c.reset()
 		c.err = io.EOF
-		// Don't return the error - next call will return io.EOF naturally
-		return chunk, nil
+		return nil, io.EOF
 	}
+	c.ended = err != nil
+	if c.silenceWindow > 0 && !c.ended && n == len(buf) {
+		if cut := c.silenceCut(buf); cut < n {
+			c.carry = append(c.carry, buf[cut:]...)
+			n = cut
+		}
+	}
+	c.peeked = true
+	c.peekedLen = n
 
-	return chunk, nil
+	return c.audio[:n], nil // Slice the buffer to actual read size
+}
+
+// noEOF turns io.EOF into io.ErrUnexpectedEOF, for reads that must not
+// hit the end of the input.
+func noEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
 }
 
 func isErrNotEOF(err error) bool {
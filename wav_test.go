@@ -2,11 +2,19 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"io"
+	"math"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"testing/iotest"
+	"time"
 )
 
 // JsonData represents the JSON structure for each chunk
@@ -47,7 +55,7 @@ func generateChunks(filename string, chunkSize int) ([][]byte, error) {
 	}
 	defer file.Close()
 
-	chunker := NewWAVChunker(file)
+	chunker := NewWAVChunker(file, WithChunkSize(chunkSize))
 	var chunks [][]byte
 
 	for {
@@ -209,3 +217,907 @@ func BenchmarkWAVChunkingHighConcurrency(b *testing.B) {
 		}
 	})
 }
+
+// makeWAV builds a canonical PCM WAV file around data.
+func makeWAV(channels, sampleRate, bitsPerSample int, data []byte) []byte {
+	blockAlign := channels * bitsPerSample / 8
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	buf.Write(writeUint32LE(uint32(36 + len(data))))
+	buf.WriteString("WAVE")
+	buf.WriteString("fmt ")
+	buf.Write(writeUint32LE(16))
+	buf.Write([]byte{1, 0, byte(channels), byte(channels >> 8)})
+	buf.Write(writeUint32LE(uint32(sampleRate)))
+	buf.Write(writeUint32LE(uint32(sampleRate * blockAlign)))
+	buf.Write([]byte{byte(blockAlign), byte(blockAlign >> 8), byte(bitsPerSample), byte(bitsPerSample >> 8)})
+	buf.WriteString("data")
+	buf.Write(writeUint32LE(uint32(len(data))))
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+// makeAudio returns n bytes of deterministic non-zero audio data.
+func makeAudio(n int, seed byte) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(i) ^ seed
+	}
+	return data
+}
+
+func TestWAVChunker8BitStereo(t *testing.T) {
+	data := makeAudio(5001, 0x80)
+
+	chunker := NewWAVChunker(bytes.NewReader(makeWAV(2, 8000, 8, data)))
+	chunker.targetSize = 44 + 1001 // odd audio size to force alignment
+	defer chunker.Close()
+
+	var audio []byte
+	for i := 0; ; i++ {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		payload := chunk[44:]
+		if len(audio)+len(payload) < len(data) && len(payload)%2 != 0 {
+			t.Errorf("chunk %d: %d audio bytes, not aligned to 2-byte frames", i, len(payload))
+		}
+		audio = append(audio, payload...)
+	}
+	if !bytes.Equal(audio, data) {
+		t.Fatalf("audio mismatch: got %d bytes, want %d bytes", len(audio), len(data))
+	}
+
+	format, err := chunker.Format()
+	if err != nil {
+		t.Fatalf("Format() error: %v", err)
+	}
+	if got := format.SampleFormat(); got != SampleFormatU8 {
+		t.Errorf("SampleFormat() = %v, want %v", got, SampleFormatU8)
+	}
+	if got := format.BlockAlign(); got != 2 {
+		t.Errorf("BlockAlign() = %d, want 2", got)
+	}
+}
+
+func TestWAVChunkerReadHeader(t *testing.T) {
+	data := makeAudio(10000, 0x44)
+	wav := makeWAV(2, 22050, 16, data)
+
+	chunker := NewWAVChunker(bytes.NewReader(wav), WithWAVMode(WAVModeHeaderless))
+	if _, err := chunker.Format(); err != ErrNoFormat {
+		t.Fatalf("Format() before ReadHeader error = %v, want ErrNoFormat", err)
+	}
+	if err := chunker.ReadHeader(); err != nil {
+		t.Fatalf("ReadHeader() error: %v", err)
+	}
+	want := WAVFormat{AudioFormat: 1, Channels: 2, SampleRate: 22050, BitsPerSample: 16, ByteRate: 88200}
+	if f, err := chunker.Format(); err != nil || f != want {
+		t.Fatalf("Format() = %+v, %v; want %+v", f, err, want)
+	}
+	if err := chunker.ReadHeader(); err != nil {
+		t.Fatalf("second ReadHeader() error: %v", err)
+	}
+
+	var audio []byte
+	for _, chunk := range readAllChunks(t, chunker) {
+		audio = append(audio, chunk...)
+	}
+	if !bytes.Equal(audio, data) {
+		t.Fatalf("got %d bytes of audio after ReadHeader, want %d", len(audio), len(data))
+	}
+
+	// A missing fmt chunk is reported by Format
+	noFmt := append(append([]byte("RIFF\x00\x00\x00\x00WAVEdata"), writeUint32LE(4)...), 1, 2, 3, 4)
+	chunker = NewWAVChunker(bytes.NewReader(noFmt))
+	if err := chunker.ReadHeader(); err != nil {
+		t.Fatalf("ReadHeader() without fmt error: %v", err)
+	}
+	if _, err := chunker.Format(); err != ErrNoFormat {
+		t.Errorf("Format() without fmt error = %v, want ErrNoFormat", err)
+	}
+}
+
+func TestWAVChunkerNextContext(t *testing.T) {
+	wav := makeWAV(2, 44100, 16, makeAudio(100000, 0x55))
+	chunker := NewWAVChunker(bytes.NewReader(wav))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := chunker.NextContext(ctx); err != nil {
+		t.Fatalf("NextContext() error: %v", err)
+	}
+	cancel()
+
+	for i := 0; i < 2; i++ {
+		if _, err := chunker.NextContext(ctx); err != context.Canceled {
+			t.Fatalf("NextContext() after cancel error = %v, want context.Canceled", err)
+		}
+		if !chunker.closed || chunker.audio != nil || chunker.header != nil {
+			t.Fatal("buffers not returned to the pools after cancel")
+		}
+	}
+	if _, err := chunker.Next(); err != context.Canceled {
+		t.Errorf("Next() after cancel error = %v, want context.Canceled", err)
+	}
+
+	// An expired deadline releases the buffers before any audio is read
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now())
+	defer cancel()
+	chunker = NewWAVChunker(bytes.NewReader(wav))
+	if _, err := chunker.NextContext(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("NextContext() error = %v, want context.DeadlineExceeded", err)
+	}
+	if !chunker.closed {
+		t.Error("buffers not returned to the pools after the deadline")
+	}
+}
+
+func TestWAVChunkerExtensibleFormat(t *testing.T) {
+	const channelMask51 = 0x3f // FL, FR, FC, LFE, BL, BR
+
+	extensible := func(subFormat uint16) []byte {
+		fmtChunk := make([]byte, 40)
+		copy(fmtChunk[0:], []byte{0xfe, 0xff, 6, 0})
+		copy(fmtChunk[4:], writeUint32LE(48000))
+		copy(fmtChunk[8:], writeUint32LE(48000*6*4))
+		copy(fmtChunk[12:], []byte{6 * 4, 0, 32, 0, 22, 0, 24, 0})
+		copy(fmtChunk[20:], writeUint32LE(channelMask51))
+		copy(fmtChunk[24:], []byte{byte(subFormat), byte(subFormat >> 8)})
+		copy(fmtChunk[26:], wavSubFormatSuffix)
+
+		var buf bytes.Buffer
+		buf.WriteString("RIFF")
+		buf.Write(writeUint32LE(0))
+		buf.WriteString("WAVEfmt ")
+		buf.Write(writeUint32LE(uint32(len(fmtChunk))))
+		buf.Write(fmtChunk)
+		buf.WriteString("data")
+		buf.Write(writeUint32LE(24 * 100))
+		buf.Write(makeAudio(24*100, 0x51))
+		return buf.Bytes()
+	}
+
+	tests := []struct {
+		subFormat uint16
+		want      SampleFormat
+	}{
+		{wavFormatPCM, SampleFormatS32},
+		{wavFormatIEEEFloat, SampleFormatF32},
+	}
+	for _, tt := range tests {
+		chunker := NewWAVChunker(bytes.NewReader(extensible(tt.subFormat)))
+		if err := chunker.ReadHeader(); err != nil {
+			t.Fatalf("ReadHeader() error: %v", err)
+		}
+		f, err := chunker.Format()
+		if err != nil {
+			t.Fatalf("Format() error: %v", err)
+		}
+		want := WAVFormat{
+			AudioFormat:        tt.subFormat,
+			Channels:           6,
+			SampleRate:         48000,
+			BitsPerSample:      32,
+			ByteRate:           48000 * 6 * 4,
+			Extensible:         true,
+			ValidBitsPerSample: 24,
+			ChannelMask:        channelMask51,
+		}
+		if f != want {
+			t.Errorf("Format() = %+v, want %+v", f, want)
+		}
+		if got := f.SampleFormat(); got != tt.want {
+			t.Errorf("SampleFormat() = %v, want %v", got, tt.want)
+		}
+		if got := f.BlockAlign(); got != 24 {
+			t.Errorf("BlockAlign() = %d, want 24", got)
+		}
+	}
+}
+
+func TestWAVChunkerTruncatedHeader(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(100, 0))
+
+	tests := []struct {
+		size int
+		what string
+	}{
+		{5, "RIFF header"},
+		{16, "chunk header"},
+		{20, "chunk data"},
+		{30, "chunk data"},
+		{40, "chunk header"},
+	}
+	for _, tt := range tests {
+		chunker := NewWAVChunker(bytes.NewReader(wav[:tt.size]))
+		_, err := chunker.Next()
+		if !errors.Is(err, ErrTruncatedHeader) || !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Errorf("truncated at %d: error = %v, want ErrTruncatedHeader", tt.size, err)
+			continue
+		}
+		if !strings.Contains(err.Error(), "reading "+tt.what) {
+			t.Errorf("truncated at %d: error = %q, want it to name the %s", tt.size, err, tt.what)
+		}
+	}
+}
+
+func TestWAVChunkerHeaderless(t *testing.T) {
+	data := makeAudio(10000, 0x33)
+	input := append(makeWAV(1, 8000, 16, data), "junk"...) // trailing bytes past the data region
+
+	chunker := NewWAVChunker(bytes.NewReader(input), WithWAVMode(WAVModeHeaderless))
+	defer chunker.Close()
+
+	var chunks [][]byte
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	var want [][]byte
+	for rest := data; len(rest) > 0; {
+		n := min(len(rest), defaultChunkSize)
+		want = append(want, rest[:n])
+		rest = rest[n:]
+	}
+
+	if len(chunks) != len(want) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(want))
+	}
+	for i := range want {
+		if !bytes.Equal(chunks[i], want[i]) {
+			t.Errorf("chunk %d mismatch: got %d bytes, want %d bytes", i, len(chunks[i]), len(want[i]))
+		}
+	}
+}
+
+// readAllChunks drains c and returns all chunks it produced.
+func readAllChunks(t testing.TB, c Chunker) [][]byte {
+	t.Helper()
+	var chunks [][]byte
+	for {
+		chunk, err := c.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		chunks = append(chunks, chunk)
+	}
+}
+
+func TestWAVChunkerChunkSize(t *testing.T) {
+	data, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunker := NewWAVChunker(bytes.NewReader(data))
+	if err := chunker.ReadHeader(); err != nil {
+		t.Fatal(err)
+	}
+	headerLen := len(chunker.header)
+	chunker.Close()
+
+	audio := -1
+	for _, size := range []int{16384, 4096} {
+		chunks := readAllChunks(t, NewWAVChunker(bytes.NewReader(data), WithChunkSize(size)))
+		total := 0
+		for i, chunk := range chunks {
+			if len(chunk) > size || i < len(chunks)-1 && len(chunk) < size-3 {
+				t.Errorf("size %d: chunk %d has %d bytes", size, i, len(chunk))
+			}
+			total += len(chunk) - headerLen
+		}
+		if audio >= 0 && total != audio {
+			t.Errorf("size %d: got %d bytes of audio, want %d", size, total, audio)
+		}
+		audio = total
+	}
+
+	// Sizes out of range fall back to the default
+	chunker = NewWAVChunker(bytes.NewReader(data), WithChunkSize(MinChunkSize-1))
+	if chunk, err := chunker.Next(); err != nil || len(chunk) != DefaultChunkSize {
+		t.Errorf("out of range size: got %d bytes, %v, want %d", len(chunk), err, DefaultChunkSize)
+	}
+}
+
+func TestWAVChunkerStreaming(t *testing.T) {
+	data := makeAudio(10002, 0x5c)
+	wav := makeWAV(2, 22050, 16, data)
+
+	chunker := NewWAVChunker(bytes.NewReader(wav), WithWAVMode(WAVModeStreaming))
+	chunker.targetSize = 4095
+	var joined []byte
+	for i := 0; ; i++ {
+		size, err := chunker.NextSize()
+		if err == io.EOF {
+			break
+		}
+		chunk, err := chunker.Next()
+		if err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		if len(chunk) != size {
+			t.Errorf("chunk %d: NextSize() = %d, len(Next()) = %d", i, size, len(chunk))
+		}
+		if i == 0 && !bytes.HasPrefix(chunk, wav[:44]) {
+			t.Errorf("first chunk does not start with the header")
+		}
+		if i > 0 && len(chunk) != 4092 && len(joined)+len(chunk) != len(wav) {
+			t.Errorf("chunk %d: got %d bytes, want 4092 bytes of whole sample frames", i, len(chunk))
+		}
+		joined = append(joined, chunk...)
+	}
+	if !bytes.Equal(joined, wav) {
+		t.Fatalf("joined chunks differ from the input: got %d bytes, want %d", len(joined), len(wav))
+	}
+	if chunker.IndependentChunks() {
+		t.Error("IndependentChunks() = true for streaming chunks")
+	}
+}
+
+func TestWAVChunkerPlanarOutput(t *testing.T) {
+	// 16-bit stereo with left samples 0, 1, 2, ... and right samples
+	// 1000, 1001, 1002, ...
+	const frames = 10
+	var data []byte
+	for i := 0; i < frames; i++ {
+		data = binary.LittleEndian.AppendUint16(data, uint16(i))
+		data = binary.LittleEndian.AppendUint16(data, uint16(1000+i))
+	}
+
+	chunker := NewWAVChunker(bytes.NewReader(makeWAV(2, 8000, 16, data)), WithWAVMode(WAVModeHeaderless), WithPlanarOutput())
+	chunker.targetSize = 14 // rounded down to 3 whole frames
+	chunks := readAllChunks(t, chunker)
+
+	wantFrames := []int{3, 3, 3, 1}
+	if len(chunks) != len(wantFrames) {
+		t.Fatalf("got %d chunks, want %d", len(chunks), len(wantFrames))
+	}
+	first := 0
+	for i, chunk := range chunks {
+		n := wantFrames[i]
+		var want []byte
+		for j := 0; j < n; j++ {
+			want = binary.LittleEndian.AppendUint16(want, uint16(first+j))
+		}
+		for j := 0; j < n; j++ {
+			want = binary.LittleEndian.AppendUint16(want, uint16(1000+first+j))
+		}
+		if !bytes.Equal(chunk, want) {
+			t.Errorf("chunk %d: got %v, want %v", i, chunk, want)
+		}
+		first += n
+	}
+
+	if f, err := chunker.Format(); err != nil || !f.Planar {
+		t.Errorf("Format() = %+v, %v; want planar layout", f, err)
+	}
+	// Complete WAV files cannot hold planar audio
+	complete := NewWAVChunker(bytes.NewReader(makeWAV(2, 8000, 16, data)), WithPlanarOutput())
+	if chunks := readAllChunks(t, complete); len(chunks) != 1 || !bytes.HasSuffix(chunks[0], data) {
+		t.Error("planar output applied in complete mode")
+	}
+}
+
+func TestWAVChunkerEmptyData(t *testing.T) {
+	for _, mode := range []WAVChunkMode{WAVModeComplete, WAVModeHeaderless} {
+		for _, input := range [][]byte{
+			makeWAV(2, 44100, 16, nil),
+			append(makeWAV(2, 44100, 16, nil), "LIST\x04\x00\x00\x00INFO"...),
+		} {
+			chunker := NewWAVChunker(bytes.NewReader(input), WithWAVMode(mode))
+			for i := 0; i < 3; i++ {
+				chunk, err := chunker.Next()
+				if chunk != nil || err != io.EOF {
+					t.Fatalf("mode %d: call %d: got %d bytes, error %v; want io.EOF only", mode, i, len(chunk), err)
+				}
+			}
+		}
+	}
+}
+
+func TestWAVChunkerUnknownDataSize(t *testing.T) {
+	data := makeAudio(30000, 0x62)
+	for _, size := range []uint32{placeholderSize, 0} {
+		wav := makeWAV(2, 44100, 16, data)
+		copy(wav[4:8], writeUint32LE(size))
+		copy(wav[40:44], writeUint32LE(size))
+
+		chunker := NewWAVChunker(bytes.NewReader(wav), WithStrict())
+		var audio []byte
+		for i := 0; ; i++ {
+			res, err := chunker.NextResult()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("size %#x: NextResult() error: %v", size, err)
+			}
+			payload := res.Data[44:]
+			if got := readUint32LE(res.Data[40:44]); got != uint32(len(payload)) {
+				t.Errorf("size %#x: chunk %d: data size %d, want %d", size, i, got, len(payload))
+			}
+			if got := readUint32LE(res.Data[4:8]); got != uint32(len(res.Data)-8) {
+				t.Errorf("size %#x: chunk %d: RIFF size %d, want %d", size, i, got, len(res.Data)-8)
+			}
+			if res.BytesTotal != 0 {
+				t.Errorf("size %#x: chunk %d: BytesTotal = %d, want 0 for an unknown size", size, i, res.BytesTotal)
+			}
+			audio = append(audio, payload...)
+		}
+		if !bytes.Equal(audio, data) {
+			t.Errorf("size %#x: got %d bytes of audio, want %d", size, len(audio), len(data))
+		}
+	}
+}
+
+func TestWAVChunkerInconsistentRIFFSize(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(1000, 1))
+	copy(wav[4:8], writeUint32LE(20)) // shorter than the fmt chunk alone
+
+	if _, err := NewWAVChunker(bytes.NewReader(wav), WithStrict()).Next(); err != ErrInconsistentRIFFSize {
+		t.Errorf("strict: got error %v, want %v", err, ErrInconsistentRIFFSize)
+	}
+	if _, err := NewWAVChunker(bytes.NewReader(wav)).Next(); err != nil {
+		t.Errorf("lenient: Next() error: %v", err)
+	}
+
+	// Streaming placeholders are consistent
+	sample, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAllChunks(t, NewWAVChunker(bytes.NewReader(sample), WithStrict()))
+}
+
+func TestWAVChunkerPadOddChunks(t *testing.T) {
+	audio := makeAudio(1001, 0x17) // 8-bit mono, odd length
+	chunker := NewWAVChunker(bytes.NewReader(makeWAV(1, 8000, 8, audio)), WithPadOddChunks())
+
+	size, err := chunker.NextSize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := readAllChunks(t, chunker)
+	if len(chunks) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(chunks))
+	}
+	chunk := chunks[0]
+
+	if want := 44 + len(audio) + 1; len(chunk) != want || size != want {
+		t.Fatalf("got %d bytes, NextSize %d; want %d", len(chunk), size, want)
+	}
+	if got := readUint32LE(chunk[40:44]); got != uint32(len(audio)) {
+		t.Errorf("data size %d, want %d", got, len(audio))
+	}
+	if got := readUint32LE(chunk[4:8]); got != uint32(len(chunk)-8) {
+		t.Errorf("riff size %d, want %d", got, len(chunk)-8)
+	}
+	if !bytes.Equal(chunk[44:44+len(audio)], audio) || chunk[len(chunk)-1] != 0 {
+		t.Error("audio not followed by a zero pad byte")
+	}
+}
+
+func TestWAVChunkerSilenceAwareBoundaries(t *testing.T) {
+	const sampleRate = 8000
+	const gapStart, gapEnd = 6400, 6800 // samples 0.80s to 0.85s
+
+	// A 440 Hz tone with a silent gap, 16-bit mono
+	var audio []byte
+	for i := 0; i < 3*sampleRate; i++ {
+		var v int16
+		if i < gapStart || i >= gapEnd {
+			v = int16(16000 * math.Sin(2*math.Pi*440*float64(i)/sampleRate))
+		}
+		audio = binary.LittleEndian.AppendUint16(audio, uint16(v))
+	}
+	wav := makeWAV(1, sampleRate, 16, audio)
+
+	chunker := NewWAVChunker(bytes.NewReader(wav), WithSilenceAwareBoundaries(300*time.Millisecond, 0.01))
+	chunker.targetSize = 44 + 2*sampleRate // one second of audio
+	chunks := readAllChunks(t, chunker)
+
+	cut := len(chunks[0]) - 44
+	if cut < 2*gapStart || cut >= 2*gapEnd {
+		t.Errorf("first chunk cut at sample %d, want within the gap [%d, %d)", cut/2, gapStart, gapEnd)
+	}
+
+	var got []byte
+	for _, chunk := range chunks {
+		got = append(got, chunk[44:]...)
+	}
+	if !bytes.Equal(got, audio) {
+		t.Error("chunks do not reassemble to the input audio")
+	}
+
+	// Without a quiet enough block the chunk is cut at the chunk size
+	chunker = NewWAVChunker(bytes.NewReader(wav), WithSilenceAwareBoundaries(100*time.Millisecond, 0.01))
+	chunker.targetSize = 44 + 2*sampleRate
+	if chunk, err := chunker.Next(); err != nil || len(chunk) != chunker.targetSize {
+		t.Errorf("loud window: got %d bytes, error %v; want %d bytes", len(chunk), err, chunker.targetSize)
+	}
+}
+
+func TestWAVChunkerMaxMetadataChunks(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(1000, 1))
+	for i := 0; i < 5000; i++ {
+		wav = insertChunk(wav, "junk", nil)
+	}
+	if len(wav) > maxHeaderSize {
+		t.Fatal("test header exceeds the size limit")
+	}
+
+	if _, err := NewWAVChunker(bytes.NewReader(wav)).Next(); err != ErrTooManyChunks {
+		t.Errorf("default limit: got error %v, want %v", err, ErrTooManyChunks)
+	}
+	if _, err := NewWAVChunker(bytes.NewReader(wav), WithMaxMetadataChunks(100)).Next(); err != ErrTooManyChunks {
+		t.Errorf("limit 100: got error %v, want %v", err, ErrTooManyChunks)
+	}
+	if _, err := NewWAVChunker(bytes.NewReader(wav), WithMaxMetadataChunks(5001)).Next(); err != nil {
+		t.Errorf("limit 5001: Next() error: %v", err)
+	}
+	if _, err := NewWAVChunker(bytes.NewReader(wav), WithMaxMetadataChunks(0)).Next(); err != nil {
+		t.Errorf("no limit: Next() error: %v", err)
+	}
+}
+
+func TestWAVChunkerWithoutPooling(t *testing.T) {
+	wav := makeWAV(2, 44100, 16, makeAudio(100001, 0x42))
+
+	pooled := readAllChunks(t, NewWAVChunker(bytes.NewReader(wav)))
+	unpooled := readAllChunks(t, NewWAVChunker(bytes.NewReader(wav), WithoutPooling()))
+
+	if len(pooled) != len(unpooled) {
+		t.Fatalf("chunk count mismatch: pooled %d, unpooled %d", len(pooled), len(unpooled))
+	}
+	for i := range pooled {
+		if !bytes.Equal(pooled[i], unpooled[i]) {
+			t.Errorf("chunk %d mismatch: pooled %d bytes, unpooled %d bytes", i, len(pooled[i]), len(unpooled[i]))
+		}
+	}
+}
+
+func TestWAVChunkerLargeDataChunk(t *testing.T) {
+	data := makeAudio(maxMetadataChunkSize*2+100, 0x77)
+	wav := insertChunk(makeWAV(2, 44100, 16, data), "LIST", []byte("INFOtest"))
+
+	const headerLen = 60 // RIFF, fmt, LIST and data chunks
+
+	var audio []byte
+	for i, chunk := range readAllChunks(t, NewWAVChunker(bytes.NewReader(wav))) {
+		if got := readUint32LE(chunk[56:60]); int(got) != len(chunk)-headerLen {
+			t.Fatalf("chunk %d: data size %d, want %d", i, got, len(chunk)-headerLen)
+		}
+		audio = append(audio, chunk[headerLen:]...)
+	}
+	if !bytes.Equal(audio, data) {
+		t.Fatalf("audio mismatch: got %d bytes, want %d bytes", len(audio), len(data))
+	}
+}
+
+func TestWAVChunkerLargeMetadataChunk(t *testing.T) {
+	wav := insertChunk(makeWAV(2, 44100, 16, makeAudio(1000, 0)), "junk", make([]byte, maxMetadataChunkSize*2))
+
+	chunker := NewWAVChunker(bytes.NewReader(wav))
+	if _, err := chunker.Next(); !errors.Is(err, ErrChunkTooLarge) {
+		t.Fatalf("got %v, want %v", err, ErrChunkTooLarge)
+	}
+}
+
+func TestWAVChunkerShortReads(t *testing.T) {
+	data := makeAudio(50002, 0x5e)
+	wav := makeWAV(2, 44100, 16, data)
+
+	readers := map[string]io.Reader{
+		"one byte": iotest.OneByteReader(bytes.NewReader(wav)),
+		"half":     iotest.HalfReader(bytes.NewReader(wav)),
+	}
+	for name, r := range readers {
+		chunks := readAllChunks(t, NewWAVChunker(r))
+
+		var audio []byte
+		for i, chunk := range chunks {
+			if i < len(chunks)-1 && len(chunk) != defaultChunkSize {
+				t.Errorf("%s: chunk %d: got %d bytes, want %d", name, i, len(chunk), defaultChunkSize)
+			}
+			audio = append(audio, chunk[44:]...)
+		}
+		if !bytes.Equal(audio, data) {
+			t.Errorf("%s: got %d audio bytes, want %d", name, len(audio), len(data))
+		}
+	}
+}
+
+// readBlocks reads r in blocks of size n using either io.ReadFull or a single
+// Read per block and returns the number of blocks read.
+func readBlocks(r io.Reader, buf []byte, full bool) (int, error) {
+	blocks := 0
+	for {
+		var n int
+		var err error
+		if full {
+			n, err = io.ReadFull(r, buf)
+		} else {
+			n, err = r.Read(buf)
+		}
+		if n > 0 {
+			blocks++
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return blocks, err
+		}
+	}
+}
+
+// BenchmarkWAVAudioReads compares io.ReadFull with plain Read for reading
+// the audio data in chunk-sized blocks from a file and from a pipe.
+// The blocks/op metric shows how many short blocks plain Read produces.
+func BenchmarkWAVAudioReads(b *testing.B) {
+	open := map[string]func(b *testing.B) io.ReadCloser{
+		"file": func(b *testing.B) io.ReadCloser {
+			file, err := os.Open("sample.wav")
+			if err != nil {
+				b.Fatal(err)
+			}
+			return file
+		},
+		"pipe": func(b *testing.B) io.ReadCloser {
+			file, err := os.Open("sample.wav")
+			if err != nil {
+				b.Fatal(err)
+			}
+			pr, pw := io.Pipe()
+			go func() {
+				// Small writes mimic a streaming producer
+				_, err := io.CopyBuffer(pw, struct{ io.Reader }{file}, make([]byte, 1500))
+				file.Close()
+				pw.CloseWithError(err)
+			}()
+			return pr
+		},
+	}
+
+	for _, source := range []string{"file", "pipe"} {
+		for _, full := range []bool{true, false} {
+			name := source + "/Read"
+			if full {
+				name = source + "/ReadFull"
+			}
+			b.Run(name, func(b *testing.B) {
+				buf := make([]byte, defaultChunkSize-44)
+				blocks := 0
+				for i := 0; i < b.N; i++ {
+					r := open[source](b)
+					n, err := readBlocks(r, buf, full)
+					if err != nil {
+						b.Fatal(err)
+					}
+					r.Close()
+					blocks += n
+				}
+				b.ReportMetric(float64(blocks)/float64(b.N), "blocks/op")
+			})
+		}
+	}
+}
+
+func TestWAVChunkerWithPools(t *testing.T) {
+	const headerCap, audioCap = 777, 9999
+
+	header := &sync.Pool{New: func() interface{} { return make([]byte, 0, headerCap) }}
+	audio := &sync.Pool{New: func() interface{} { return make([]byte, audioCap) }}
+
+	// sync.Pool may drop buffers, so give it a few chances to keep one
+	var chunkers []*WAVChunker
+	for i := 0; i < 10; i++ {
+		c := NewWAVChunker(bytes.NewReader(makeWAV(1, 8000, 16, makeAudio(100, 0))), WithPools(header, audio))
+		if _, err := c.Next(); err != nil {
+			t.Fatalf("Next() error: %v", err)
+		}
+		chunkers = append(chunkers, c)
+	}
+	for _, c := range chunkers {
+		c.Close()
+	}
+
+	for i := 0; i < 20; i++ {
+		if buf := headerBufferPool.Get().([]byte); cap(buf) == headerCap {
+			t.Fatal("custom header buffer returned to the global pool")
+		}
+		if buf := audioBufferPool.Get().([]byte); len(buf) == audioCap {
+			t.Fatal("custom audio buffer returned to the global pool")
+		}
+	}
+
+	// Disable New to see only the buffers that were put back
+	header.New, audio.New = nil, nil
+	var headerFound, audioFound bool
+	for i := 0; i < len(chunkers); i++ {
+		if buf, _ := header.Get().([]byte); cap(buf) == headerCap {
+			headerFound = true
+		}
+		if buf, _ := audio.Get().([]byte); len(buf) == audioCap {
+			audioFound = true
+		}
+	}
+	if !headerFound {
+		t.Errorf("header buffer not returned to the custom pool")
+	}
+	if !audioFound {
+		t.Errorf("audio buffer not returned to the custom pool")
+	}
+}
+
+func TestWAVChunkerGaplessPayloads(t *testing.T) {
+	const frameSize = 6 // 24-bit stereo
+
+	data := makeAudio(frameSize*12345, 0x6b)
+	for _, targetSize := range []int{defaultChunkSize, 44 + 1000, 44 + 4099} {
+		chunker := NewWAVChunker(bytes.NewReader(makeWAV(2, 48000, 24, data)))
+		chunker.targetSize = targetSize
+
+		var audio []byte
+		for i, chunk := range readAllChunks(t, chunker) {
+			payload := chunk[44:]
+			if len(payload)%frameSize != 0 {
+				t.Errorf("target %d: chunk %d: %d audio bytes, not frame-aligned", targetSize, i, len(payload))
+			}
+			audio = append(audio, payload...)
+		}
+		if !bytes.Equal(audio, data) {
+			t.Errorf("target %d: concatenated audio differs from the data region", targetSize)
+		}
+	}
+}
+
+func TestWAVChunkerUnalignedDataSize(t *testing.T) {
+	const frameSize = 4 // 16-bit stereo
+
+	// The data region ends with half a frame
+	data := makeAudio(frameSize*1000+2, 0x2d)
+	chunker := NewWAVChunker(bytes.NewReader(makeWAV(2, 44100, 16, data)))
+	chunker.targetSize = 44 + 1001
+
+	chunks := readAllChunks(t, chunker)
+	var audio []byte
+	for i, chunk := range chunks {
+		payload := chunk[44:]
+		if i < len(chunks)-1 && len(payload)%frameSize != 0 {
+			t.Errorf("chunk %d: %d audio bytes, not frame-aligned", i, len(payload))
+		}
+		audio = append(audio, payload...)
+	}
+	if last := chunks[len(chunks)-1][44:]; len(last)%frameSize != 2 {
+		t.Errorf("last chunk has %d audio bytes, want the trailing half frame", len(last))
+	}
+	if !bytes.Equal(audio, data) {
+		t.Error("concatenated audio differs from the data region")
+	}
+}
+
+func TestWAVChunkerNextSize(t *testing.T) {
+	wav := makeWAV(2, 44100, 16, makeAudio(30002, 0x19))
+
+	for _, mode := range []WAVChunkMode{WAVModeComplete, WAVModeHeaderless} {
+		chunker := NewWAVChunker(bytes.NewReader(wav), WithWAVMode(mode))
+		for i := 0; ; i++ {
+			size, err := chunker.NextSize()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("mode %d: NextSize() error: %v", mode, err)
+			}
+			// Repeated calls must not consume more input
+			if again, err := chunker.NextSize(); err != nil || again != size {
+				t.Fatalf("mode %d: chunk %d: NextSize() = %d, %v, want %d", mode, i, again, err, size)
+			}
+			chunk, err := chunker.Next()
+			if err != nil {
+				t.Fatalf("mode %d: Next() error: %v", mode, err)
+			}
+			if len(chunk) != size {
+				t.Errorf("mode %d: chunk %d: NextSize() = %d, len(Next()) = %d", mode, i, size, len(chunk))
+			}
+		}
+	}
+}
+
+func TestWAVChunkerEffectiveChunkSize(t *testing.T) {
+	// 16-bit stereo, so whole sample frames are 4 bytes
+	wav := makeWAV(2, 44100, 16, makeAudio(30000, 0x2a))
+
+	chunker := NewWAVChunker(bytes.NewReader(wav))
+	chunker.targetSize = 44 + 2047
+	if n := chunker.EffectiveChunkSize(); n != 0 {
+		t.Fatalf("EffectiveChunkSize() before ReadHeader = %d, want 0", n)
+	}
+	if err := chunker.ReadHeader(); err != nil {
+		t.Fatalf("ReadHeader() error: %v", err)
+	}
+	if n := chunker.EffectiveChunkSize(); n != 2044 {
+		t.Fatalf("EffectiveChunkSize() = %d, want 2044", n)
+	}
+	chunk, err := chunker.Next()
+	if err != nil {
+		t.Fatalf("Next() error: %v", err)
+	}
+	if n := len(chunk) - 44; n != 2044 {
+		t.Errorf("first chunk holds %d audio bytes, want 2044", n)
+	}
+}
+
+func TestNewWAVFromPCM(t *testing.T) {
+	pcm := makeAudio(4*5000, 0x2d) // 16-bit stereo frames
+	want := WAVFormat{AudioFormat: 1, Channels: 2, SampleRate: 22050, BitsPerSample: 16, ByteRate: 22050 * 4}
+
+	chunks := readAllChunks(t, NewWAVFromPCM(bytes.NewReader(pcm), 22050, 2, 16, 44+1026))
+	if len(chunks) == 0 {
+		t.Fatal("no chunks")
+	}
+
+	var audio []byte
+	for i, chunk := range chunks {
+		// Every chunk must parse as a standalone WAV file
+		c := NewWAVChunker(bytes.NewReader(chunk))
+		parsed := readAllChunks(t, c)
+		if len(parsed) != 1 || !bytes.Equal(parsed[0], chunk) {
+			t.Fatalf("chunk %d is not a valid WAV file", i)
+		}
+		if format, err := c.Format(); err != nil || format != want {
+			t.Fatalf("chunk %d: Format() = %+v, %v, want %+v", i, format, err, want)
+		}
+		if i < len(chunks)-1 && len(chunk) != 44+1024 {
+			t.Errorf("chunk %d: got %d bytes, want %d", i, len(chunk), 44+1024)
+		}
+		audio = append(audio, chunk[44:]...)
+	}
+	if !bytes.Equal(audio, pcm) {
+		t.Fatalf("got %d audio bytes, want %d", len(audio), len(pcm))
+	}
+}
+
+// slowReader returns a single byte per read after a delay.
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (s *slowReader) Read(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.r.Read(p[:1])
+}
+
+func TestWAVChunkerHeaderTimeout(t *testing.T) {
+	wav := makeWAV(1, 8000, 16, makeAudio(100, 0))
+
+	r := &slowReader{r: bytes.NewReader(wav), delay: time.Millisecond}
+	chunker := NewWAVChunker(r, WithHeaderTimeout(10*time.Millisecond))
+	if _, err := chunker.Next(); !errors.Is(err, ErrHeaderTimeout) {
+		t.Fatalf("got %v, want %v", err, ErrHeaderTimeout)
+	}
+
+	chunker = NewWAVChunker(bytes.NewReader(wav), WithHeaderTimeout(time.Second))
+	if _, err := chunker.Next(); err != nil {
+		t.Fatalf("Next() error: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"io"
+)
+
+// wavlReader reads the audio of a wave list, a LIST chunk of type wavl
+// holding data chunks interleaved with slnt chunks that stand for runs of
+// silence. It yields the audio of the data chunks with the silent runs
+// expanded to zeros, and io.EOF at the end of the list.
+type wavlReader struct {
+	r     io.Reader
+	left  int64 // bytes of the list not read yet
+	frame int   // bytes per sample frame
+	data  int64 // bytes left in the current data chunk
+	pad   bool  // the current data chunk is followed by a pad byte
+	zeros int64 // bytes of silence left
+	hdr   [8]byte
+}
+
+func (w *wavlReader) Read(p []byte) (int, error) {
+	for w.data == 0 && w.zeros == 0 {
+		if err := w.nextChunk(); err != nil {
+			return 0, err
+		}
+	}
+	if w.zeros > 0 {
+		n := int(min(int64(len(p)), w.zeros))
+		clear(p[:n])
+		w.zeros -= int64(n)
+		return n, nil
+	}
+	n, err := w.r.Read(p[:min(int64(len(p)), w.data)])
+	w.data -= int64(n)
+	w.left -= int64(n)
+	if err == io.EOF && w.data > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// nextChunk reads the header of the next subchunk of the list, skipping
+// the pad byte of the previous data chunk and chunks of other types.
+func (w *wavlReader) nextChunk() error {
+	if w.pad {
+		w.pad = false
+		if err := w.skip(1); err != nil {
+			return err
+		}
+	}
+	if w.left < int64(len(w.hdr)) {
+		return io.EOF
+	}
+	if _, err := io.ReadFull(w.r, w.hdr[:]); err != nil {
+		return err
+	}
+	w.left -= int64(len(w.hdr))
+
+	size := int64(readUint32LE(w.hdr[4:8]))
+	if size > w.left {
+		size = w.left
+	}
+	switch {
+	case compareID(w.hdr[0:4], "data"):
+		w.data = size
+		w.pad = size%2 == 1
+		return nil
+	case compareID(w.hdr[0:4], "slnt") && size >= 4:
+		if _, err := io.ReadFull(w.r, w.hdr[:4]); err != nil {
+			return err
+		}
+		w.left -= 4
+		w.zeros = int64(readUint32LE(w.hdr[:4])) * int64(w.frame)
+		size -= 4
+	}
+	return w.skip(size + size%2)
+}
+
+// skip discards the next n bytes of the list.
+func (w *wavlReader) skip(n int64) error {
+	n = min(n, w.left)
+	m, err := io.CopyN(io.Discard, w.r, n)
+	w.left -= m
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// startWaveList prepares reading the audio of a wave list whose body of
+// size bytes, following its list type, comes next in the input. The list
+// header at the end of the header is replaced with a data chunk header,
+// as every chunk holds plain audio. The length of the expanded audio is
+// not known up front, so it is read until the end of the list.
+func (c *WAVChunker) startWaveList(size uint32) error {
+	if !c.hasFormat {
+		return errors.New("wav wave list precedes the fmt chunk")
+	}
+	c.header = append(c.header[:len(c.header)-8], "data"...)
+	c.header = append(c.header, 0, 0, 0, 0)
+	c.dataStart = int64(len(c.header))
+	c.dataSizeOffset = int64(len(c.header) - 4)
+	c.bytesRead = int64(len(c.header))
+	c.unbounded = true
+	c.wavl = &wavlReader{left: int64(size), frame: c.format.BlockAlign()}
+	return nil
+}
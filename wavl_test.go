@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// makeWaveList returns a 16-bit mono WAV file whose audio is held by a
+// wave list of a data chunk, a silent run of silent samples and a second
+// data chunk, followed by an INFO list.
+func makeWaveList(first []byte, silent int, second []byte) []byte {
+	var list bytes.Buffer
+	list.WriteString("wavl")
+	list.WriteString("data")
+	list.Write(writeUint32LE(uint32(len(first))))
+	list.Write(first)
+	list.WriteString("slnt")
+	list.Write(writeUint32LE(4))
+	list.Write(writeUint32LE(uint32(silent)))
+	list.WriteString("data")
+	list.Write(writeUint32LE(uint32(len(second))))
+	list.Write(second)
+
+	wav := makeWAV(1, 8000, 16, nil)
+	wav = wav[:len(wav)-8] // drop the empty data chunk
+	wav = append(wav, "LIST"...)
+	wav = append(wav, writeUint32LE(uint32(list.Len()))...)
+	wav = append(wav, list.Bytes()...)
+	wav = append(wav, "LIST"...)
+	wav = append(wav, writeUint32LE(8)...)
+	wav = append(wav, "INFOjunk"...)
+	return wav
+}
+
+func TestWAVChunkerWaveList(t *testing.T) {
+	first, second := makeAudio(1000, 0x11), makeAudio(2000, 0x22)
+	wav := makeWaveList(first, 300, second)
+	want := append(append(append([]byte(nil), first...), make([]byte, 600)...), second...)
+
+	chunker := NewWAVChunker(bytes.NewReader(wav), WithWAVMode(WAVModeHeaderless))
+	chunker.targetSize = 1024
+	var got []byte
+	for _, chunk := range readAllChunks(t, chunker) {
+		got = append(got, chunk...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("got %d bytes of audio, want %d bytes with the silence expanded", len(got), len(want))
+	}
+
+	// Complete chunks carry a plain data chunk instead of the list
+	chunker = NewWAVChunker(bytes.NewReader(wav))
+	chunker.targetSize = 1024
+	chunks := readAllChunks(t, chunker)
+	got = nil
+	for i, chunk := range chunks {
+		sub := NewWAVChunker(bytes.NewReader(chunk), WithWAVMode(WAVModeHeaderless))
+		audio := readAllChunks(t, sub)
+		if len(audio) != 1 || bytes.Contains(chunk[:sub.dataStart], []byte("wavl")) {
+			t.Fatalf("chunk %d is not a plain WAV file", i)
+		}
+		got = append(got, audio[0]...)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("complete chunks hold %d bytes of audio, want %d", len(got), len(want))
+	}
+}
@@ -0,0 +1,224 @@
+package main
+
+import "bytes"
+
+// LoopType describes how a sampler plays a loop.
+type LoopType uint32
+
+// Loop types defined by the smpl chunk.
+const (
+	LoopForward     LoopType = 0 // play from start to end
+	LoopAlternating LoopType = 1 // play forward and backward
+	LoopBackward    LoopType = 2 // play from end to start
+)
+
+// Loop is a sample loop read from the smpl chunk.
+// Start and End are sample frame offsets, both inclusive.
+type Loop struct {
+	CuePointID uint32
+	Type       LoopType
+	Start      uint32
+	End        uint32
+	Fraction   uint32
+	PlayCount  uint32 // 0 means infinite
+}
+
+const (
+	smplHeaderSize = 36
+	smplLoopSize   = 24
+)
+
+// parseMetadataChunk extracts known metadata from a non-data chunk.
+// Unknown chunks are ignored, they are kept verbatim in the header anyway.
+func (c *WAVChunker) parseMetadataChunk(id, data []byte) {
+	switch {
+	case compareID(id, "smpl"):
+		c.loops = parseSmplChunk(data)
+	case compareID(id, "iXML"):
+		c.ixml, c.hasIXML = parseXMLChunk(data), true
+	case compareID(id, "aXML"):
+		c.axml, c.hasAXML = parseXMLChunk(data), true
+	case compareID(id, "cart"):
+		c.cart = parseCartChunk(data)
+	case compareID(id, "bext"):
+		c.bext = parseBextChunk(data)
+		if len(data) >= bextTimeRefOffset+8 {
+			// The payload is appended to the header right after
+			c.bextOffset = len(c.header)
+		}
+	}
+}
+
+// stripMetadata reduces the header, once the first chunk carried it in
+// full, to the RIFF header, the fmt chunk and the data chunk header, plus
+// the fixed part of the bext chunk when it carries the time reference.
+// The sizes are patched into every chunk anyway.
+func (c *WAVChunker) stripMetadata() {
+	h := make([]byte, 0, 44)
+	h = append(h, c.header[:12]...)
+	if c.fmtSize > 0 {
+		h = append(h, "fmt "...)
+		h = append(h, writeUint32LE(uint32(c.fmtSize))...)
+		c.fmtOffset, h = len(h), append(h, c.fmtChunk()...)
+		if c.fmtSize%2 == 1 {
+			h = append(h, 0)
+		}
+	}
+	if c.timeRef && c.bextOffset > 0 {
+		h = append(h, "bext"...)
+		h = append(h, writeUint32LE(bextFixedSize)...)
+		// A short source bext chunk still holds the time reference, the
+		// rest of the fixed part is zero-padded
+		size := int(readUint32LE(c.header[c.bextOffset-4 : c.bextOffset]))
+		bext := c.header[c.bextOffset:min(c.bextOffset+size, c.bextOffset+bextFixedSize, len(c.header))]
+		c.bextOffset, h = len(h), append(h, bext...)
+		h = append(h, make([]byte, bextFixedSize-len(bext))...)
+	}
+	h = append(h, c.header[len(c.header)-8:]...)
+	c.header = append(c.header[:0], h...)
+	c.dataSizeOffset = int64(len(c.header) - 4)
+}
+
+// parseSmplChunk decodes the loops of a smpl chunk, ignoring loops
+// that do not fit into data.
+func parseSmplChunk(data []byte) []Loop {
+	if len(data) < smplHeaderSize {
+		return nil
+	}
+	count := int(readUint32LE(data[28:32]))
+	data = data[smplHeaderSize:]
+	if count > len(data)/smplLoopSize {
+		count = len(data) / smplLoopSize
+	}
+
+	loops := make([]Loop, 0, count)
+	for i := 0; i < count; i++ {
+		l := data[i*smplLoopSize:]
+		loops = append(loops, Loop{
+			CuePointID: readUint32LE(l[0:4]),
+			Type:       LoopType(readUint32LE(l[4:8])),
+			Start:      readUint32LE(l[8:12]),
+			End:        readUint32LE(l[12:16]),
+			Fraction:   readUint32LE(l[16:20]),
+			PlayCount:  readUint32LE(l[20:24]),
+		})
+	}
+	return loops
+}
+
+// SampleLoops returns the loops parsed from the smpl chunk, if any.
+// It is available after the first call to Next.
+func (c *WAVChunker) SampleLoops() []Loop {
+	return c.loops
+}
+
+// parseXMLChunk returns the XML payload of an iXML or aXML chunk
+// without the trailing NUL padding some writers add.
+func parseXMLChunk(data []byte) string {
+	return string(bytes.TrimRight(data, "\x00"))
+}
+
+// IXML returns the XML document of the iXML chunk and whether it was present.
+// It is available after the first call to Next.
+func (c *WAVChunker) IXML() (string, bool) {
+	return c.ixml, c.hasIXML
+}
+
+// AXML returns the XML document of the aXML chunk and whether it was present.
+// It is available after the first call to Next.
+func (c *WAVChunker) AXML() (string, bool) {
+	return c.axml, c.hasAXML
+}
+
+// CartTimer is a cue timer of the cart chunk. Value is a sample offset.
+type CartTimer struct {
+	Usage string // four-character code, e.g. "SEG1"
+	Value uint32
+}
+
+// CartInfo holds the AES46 broadcast cart chunk used by radio automation.
+// Dates are formatted as yyyy-mm-dd and times as hh:mm:ss.
+type CartInfo struct {
+	Version            string
+	Title              string
+	Artist             string
+	CutID              string
+	ClientID           string
+	Category           string
+	Classification     string
+	OutCue             string
+	StartDate          string
+	StartTime          string
+	EndDate            string
+	EndTime            string
+	ProducerAppID      string
+	ProducerAppVersion string
+	UserDef            string
+	LevelReference     int32
+	PostTimers         []CartTimer // timers with an empty usage are omitted
+	URL                string
+	TagText            string
+}
+
+const (
+	cartTimerCount = 8
+	cartFixedSize  = 2048 // size of the cart chunk without the tag text
+)
+
+// parseCartChunk decodes a cart chunk. Fields missing from a short
+// chunk are left empty.
+func parseCartChunk(data []byte) *CartInfo {
+	fixed := make([]byte, cartFixedSize)
+	copy(fixed, data)
+
+	off := 0
+	text := func(n int) string {
+		s := string(bytes.TrimRight(fixed[off:off+n], "\x00 "))
+		off += n
+		return s
+	}
+
+	info := &CartInfo{
+		Version:            text(4),
+		Title:              text(64),
+		Artist:             text(64),
+		CutID:              text(64),
+		ClientID:           text(64),
+		Category:           text(64),
+		Classification:     text(64),
+		OutCue:             text(64),
+		StartDate:          text(10),
+		StartTime:          text(8),
+		EndDate:            text(10),
+		EndTime:            text(8),
+		ProducerAppID:      text(64),
+		ProducerAppVersion: text(64),
+		UserDef:            text(64),
+	}
+	info.LevelReference = int32(readUint32LE(fixed[off : off+4]))
+	off += 4
+	for i := 0; i < cartTimerCount; i++ {
+		usage := text(4)
+		value := readUint32LE(fixed[off : off+4])
+		off += 4
+		if usage != "" {
+			info.PostTimers = append(info.PostTimers, CartTimer{Usage: usage, Value: value})
+		}
+	}
+	off += 276 // reserved
+	info.URL = text(1024)
+	if len(data) > cartFixedSize {
+		info.TagText = string(bytes.TrimRight(data[cartFixedSize:], "\x00"))
+	}
+	return info
+}
+
+// Cart returns the parsed cart chunk and whether it was present. The chunk
+// itself is kept in the header of complete-mode chunks. It is available
+// after the first call to Next.
+func (c *WAVChunker) Cart() (CartInfo, bool) {
+	if c.cart == nil {
+		return CartInfo{}, false
+	}
+	return *c.cart, true
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// insertChunk inserts a RIFF chunk right after the fmt chunk of a canonical
+// WAV built by makeWAV and fixes up the RIFF size.
+func insertChunk(wav []byte, id string, data []byte) []byte {
+	var chunk bytes.Buffer
+	chunk.WriteString(id)
+	chunk.Write(writeUint32LE(uint32(len(data))))
+	chunk.Write(data)
+	if len(data)%2 == 1 {
+		chunk.WriteByte(0)
+	}
+
+	out := append([]byte(nil), wav[:36]...)
+	out = append(out, chunk.Bytes()...)
+	out = append(out, wav[36:]...)
+	copy(out[4:8], writeUint32LE(uint32(len(out)-8)))
+	return out
+}
+
+func TestWAVChunkerSampleLoops(t *testing.T) {
+	smpl := make([]byte, smplHeaderSize+smplLoopSize)
+	copy(smpl[28:32], writeUint32LE(1))
+	loop := smpl[smplHeaderSize:]
+	copy(loop[0:4], writeUint32LE(7))
+	copy(loop[4:8], writeUint32LE(uint32(LoopAlternating)))
+	copy(loop[8:12], writeUint32LE(100))
+	copy(loop[12:16], writeUint32LE(900))
+
+	wav := insertChunk(makeWAV(1, 8000, 16, makeAudio(2000, 1)), "smpl", smpl)
+	chunker := NewWAVChunker(bytes.NewReader(wav))
+	defer chunker.Close()
+
+	if _, err := chunker.Next(); err != nil {
+		t.Fatalf("Next() error: %v", err)
+	}
+
+	want := []Loop{{CuePointID: 7, Type: LoopAlternating, Start: 100, End: 900}}
+	if got := chunker.SampleLoops(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SampleLoops() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWAVChunkerIXML(t *testing.T) {
+	const ixml = `<?xml version="1.0"?><BWFXML><SCENE>12A</SCENE><TAKE>3</TAKE></BWFXML>`
+
+	wav := insertChunk(makeWAV(1, 8000, 16, makeAudio(20000, 1)), "iXML", []byte(ixml+"\x00"))
+	chunker := NewWAVChunker(bytes.NewReader(wav))
+	defer chunker.Close()
+
+	chunks := readAllChunks(t, chunker)
+
+	got, ok := chunker.IXML()
+	if !ok || got != ixml {
+		t.Fatalf("IXML() = %q, %v, want %q, true", got, ok, ixml)
+	}
+	if _, ok := chunker.AXML(); ok {
+		t.Errorf("AXML() reported a chunk that is not present")
+	}
+	for i, chunk := range chunks {
+		if !bytes.Contains(chunk, []byte("iXML")) || !bytes.Contains(chunk, []byte(ixml)) {
+			t.Errorf("chunk %d: iXML chunk not preserved", i)
+		}
+	}
+}
+
+func TestWAVChunkerCart(t *testing.T) {
+	cart := make([]byte, cartFixedSize)
+	put := func(off int, s string) { copy(cart[off:], s) }
+	put(0, "0101")
+	put(4, "Morning Show Intro")
+	put(68, "Station Voice")
+	put(132, "CUT-0042")
+	put(452, "2024-01-01")
+	put(462, "06:00:00")
+	copy(cart[680:684], writeUint32LE(uint32(0x8000)))
+	put(684, "INT ")
+	copy(cart[688:692], writeUint32LE(44100))
+	put(1024, "https://example.com/cut/42")
+	cart = append(cart, "tag text"...)
+
+	audio := makeAudio(20000, 1)
+	wav := insertChunk(makeWAV(1, 8000, 16, audio), "cart", cart)
+	chunker := NewWAVChunker(bytes.NewReader(wav))
+	defer chunker.Close()
+
+	chunks := readAllChunks(t, chunker)
+
+	got, ok := chunker.Cart()
+	if !ok {
+		t.Fatal("Cart() not present")
+	}
+	want := CartInfo{
+		Version:        "0101",
+		Title:          "Morning Show Intro",
+		Artist:         "Station Voice",
+		CutID:          "CUT-0042",
+		StartDate:      "2024-01-01",
+		StartTime:      "06:00:00",
+		LevelReference: 0x8000,
+		PostTimers:     []CartTimer{{Usage: "INT", Value: 44100}},
+		URL:            "https://example.com/cut/42",
+		TagText:        "tag text",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Cart() = %+v, want %+v", got, want)
+	}
+
+	for i, chunk := range chunks {
+		if !bytes.Contains(chunk, cart) {
+			t.Errorf("chunk %d: cart chunk not preserved", i)
+		}
+	}
+}
+
+func TestWAVChunkerWithoutMetadata(t *testing.T) {
+	data := makeAudio(20000, 0x71)
+	info := []byte("INFOINAM\x06\x00\x00\x00Title\x00")
+	wav := insertChunk(makeWAV(2, 44100, 16, data), "LIST", info)
+
+	chunker := NewWAVChunker(bytes.NewReader(wav), WithMetadata(false))
+	var audio []byte
+	for i, chunk := range readAllChunks(t, chunker) {
+		hasInfo := bytes.Contains(chunk, info)
+		if i == 0 && !hasInfo {
+			t.Errorf("first chunk lacks the LIST chunk")
+		}
+		if i > 0 && hasInfo {
+			t.Errorf("chunk %d carries the LIST chunk", i)
+		}
+		offset, err := wavDataOffset(chunk)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		if i > 0 && (offset != 44 || !bytes.Equal(chunk[8:36], wav[8:36])) {
+			t.Errorf("chunk %d: header is not the canonical header", i)
+		}
+		if size := int(readUint32LE(chunk[4:8])); size != len(chunk)-8 {
+			t.Errorf("chunk %d: RIFF size %d, want %d", i, size, len(chunk)-8)
+		}
+		audio = append(audio, chunk[offset:]...)
+	}
+	if !bytes.Equal(audio, data) {
+		t.Error("audio of the chunks differs from the input")
+	}
+}
+
+func TestWAVChunkerWithoutMetadataShortBext(t *testing.T) {
+	// A bext chunk holding the time reference but not the whole fixed part
+	bext := make([]byte, 400)
+	copy(bext, "Description")
+	binary.LittleEndian.PutUint64(bext[bextTimeRefOffset:], 48000)
+	data := makeAudio(40000, 0x33)
+	wav := insertChunk(makeWAV(2, 48000, 16, data), "bext", bext)
+
+	chunker := NewWAVChunker(bytes.NewReader(wav), WithChunkSize(8192), WithBextTimeReference(), WithMetadata(false))
+	chunks := readAllChunks(t, chunker)
+	if len(chunks) < 2 {
+		t.Fatalf("got %d chunks, want several", len(chunks))
+	}
+	var audio []byte
+	for i, chunk := range chunks {
+		c := NewWAVChunker(bytes.NewReader(chunk))
+		readAllChunks(t, c)
+		info, ok := c.Bext()
+		if !ok {
+			t.Fatalf("chunk %d lacks the bext chunk", i)
+		}
+		if want := 48000 + uint64(len(audio)/4); info.TimeReference != want {
+			t.Errorf("chunk %d: time reference %d, want %d", i, info.TimeReference, want)
+		}
+		if i > 0 && info.Description != "Description" {
+			t.Errorf("chunk %d: description %q, want the source one", i, info.Description)
+		}
+		offset, err := wavDataOffset(chunk)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		audio = append(audio, chunk[offset:]...)
+	}
+	if !bytes.Equal(audio, data) {
+		t.Error("audio of the chunks differs from the input")
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+// WriterChunker chunks data pushed to it through Write instead of pulling
+// it from an io.Reader. Every time at least the target size is buffered,
+// the callback receives a chunk whose length is the target size rounded
+// down to a multiple of the alignment, such as the WAV block align.
+//
+// The callback must not retain the chunk after it returns.
+type WriterChunker struct {
+	fn         func(chunk []byte) error
+	targetSize int
+	buf        []byte
+	err        error
+}
+
+// NewWriterChunker returns a new WriterChunker that hands chunks of
+// chunkSize bytes, aligned to align bytes, to fn. An align of 0 or 1
+// disables alignment. Writes fail with ErrInvalidChunkSize for a chunk
+// size outside [MinChunkSize, MaxChunkSize].
+func NewWriterChunker(chunkSize, align int, fn func(chunk []byte) error) *WriterChunker {
+	err := validateChunkSize(chunkSize)
+	if align > 1 {
+		chunkSize -= chunkSize % align
+		if chunkSize == 0 {
+			chunkSize = align
+		}
+	}
+	return &WriterChunker{
+		fn:         fn,
+		targetSize: chunkSize,
+		err:        err,
+	}
+}
+
+// Write buffers p and emits every complete chunk. A callback error is
+// returned and makes all further writes fail.
+func (w *WriterChunker) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.buf = append(w.buf, p...)
+	off := 0
+	for len(w.buf)-off >= w.targetSize {
+		if err := w.emit(w.buf[off : off+w.targetSize]); err != nil {
+			return len(p), err
+		}
+		off += w.targetSize
+	}
+	w.buf = append(w.buf[:0], w.buf[off:]...)
+	return len(p), nil
+}
+
+// Flush emits the buffered remainder as a final, possibly short chunk.
+// It is a no-op when nothing is buffered.
+func (w *WriterChunker) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if len(w.buf) == 0 {
+		return nil
+	}
+	err := w.emit(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
+
+// emit passes chunk to the callback, recording a failure.
+func (w *WriterChunker) emit(chunk []byte) error {
+	if err := w.fn(chunk); err != nil {
+		w.err = err
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriterChunker(t *testing.T) {
+	const chunkSize, align = 1024, 6 // 16-bit audio with three channels
+	input := makeAudio(10007, 0x21)
+
+	var chunks [][]byte
+	w := NewWriterChunker(chunkSize, align, func(chunk []byte) error {
+		chunks = append(chunks, append([]byte(nil), chunk...))
+		return nil
+	})
+
+	// Push the input in irregular increments
+	for off, i := 0, 0; off < len(input); i++ {
+		n := min(1+i*37%701, len(input)-off)
+		if _, err := w.Write(input[off : off+n]); err != nil {
+			t.Fatal(err)
+		}
+		off += n
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := chunkSize - chunkSize%align
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if len(chunk) != want {
+			t.Errorf("chunk %d: got %d bytes, want %d", i, len(chunk), want)
+		}
+	}
+	if last := chunks[len(chunks)-1]; len(last) != len(input)%want {
+		t.Errorf("last chunk: got %d bytes, want %d", len(last), len(input)%want)
+	}
+	if got := bytes.Join(chunks, nil); !bytes.Equal(got, input) {
+		t.Error("chunks do not reassemble to the input")
+	}
+
+	errStop := errors.New("stop")
+	w = NewWriterChunker(chunkSize, 1, func([]byte) error { return errStop })
+	if _, err := w.Write(input); err != errStop {
+		t.Fatalf("got error %v, want %v", err, errStop)
+	}
+	if _, err := w.Write(input); err != errStop {
+		t.Fatalf("got error %v after failure, want %v", err, errStop)
+	}
+
+	for _, size := range []int{0, -1} {
+		w = NewWriterChunker(size, 1, func([]byte) error {
+			t.Fatalf("chunk size %d: callback called", size)
+			return nil
+		})
+		if _, err := w.Write([]byte("hello")); !errors.Is(err, ErrInvalidChunkSize) {
+			t.Errorf("chunk size %d: got error %v, want ErrInvalidChunkSize", size, err)
+		}
+		if err := w.Flush(); !errors.Is(err, ErrInvalidChunkSize) {
+			t.Errorf("chunk size %d: Flush() error %v, want ErrInvalidChunkSize", size, err)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import "io"
+
+// writeChunks writes the chunks returned by next to w until next fails,
+// returning the number of bytes written and the first error other than
+// io.EOF.
+func writeChunks(w io.Writer, next func() ([]byte, error)) (int64, error) {
+	var total int64
+	for {
+		chunk, err := next()
+		if err == io.EOF {
+			return total, nil
+		}
+		if err != nil {
+			return total, err
+		}
+		n, err := w.Write(chunk)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+}
+
+// WriteTo implements io.WriterTo by writing all remaining chunks to w
+// back to back. Each chunk carries its bit reservoir, so the output
+// repeats the reservoir bytes; use NewMP3Chunker with a reservoir size of
+// 0 to reassemble the stream.
+func (c *MP3Chunker) WriteTo(w io.Writer) (int64, error) {
+	return writeChunks(w, c.Next)
+}
+
+// WriteTo implements io.WriterTo by writing all remaining chunks to w
+// back to back. In complete mode this is a sequence of complete WAV files
+// rather than a single valid one; WAVModeStreaming yields a single WAV
+// stream instead.
+func (c *WAVChunker) WriteTo(w io.Writer) (int64, error) {
+	return writeChunks(w, c.Next)
+}
+
+// WriteTo implements io.WriterTo by writing all remaining chunks to w,
+// which reproduces the input unless a final chunk policy drops data.
+func (c *DumbChunker) WriteTo(w io.Writer) (int64, error) {
+	return writeChunks(w, c.Next)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestWriteTo(t *testing.T) {
+	wav, err := os.ReadFile("sample.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mp3, err := os.ReadFile("sample.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		new  func() Chunker
+	}{
+		{"wav", func() Chunker { return NewWAVChunker(bytes.NewReader(wav)) }},
+		{"wav streaming", func() Chunker { return NewWAVChunker(bytes.NewReader(wav), WithWAVMode(WAVModeStreaming)) }},
+		{"mp3", func() Chunker { return NewMP3Chunker(bytes.NewReader(mp3), 8192, 0) }},
+		{"dumb", func() Chunker { return NewDumbChunker(bytes.NewReader(wav), 4096) }},
+	}
+	for _, tt := range tests {
+		want := bytes.Join(readAllChunks(t, tt.new()), nil)
+		var buf bytes.Buffer
+		n, err := tt.new().(io.WriterTo).WriteTo(&buf)
+		if err != nil {
+			t.Fatalf("%s: WriteTo() error: %v", tt.name, err)
+		}
+		if n != int64(len(want)) || !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("%s: WriteTo() wrote %d bytes, want the %d bytes of the joined chunks", tt.name, n, len(want))
+		}
+	}
+
+	// The streaming WAV chunks form the input again
+	var buf bytes.Buffer
+	if _, err := NewWAVChunker(bytes.NewReader(wav), WithWAVMode(WAVModeStreaming)).WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(wav, buf.Bytes()) {
+		t.Error("streaming WriteTo() output is not a prefix of the input")
+	}
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	data, err := os.ReadFile("sample.wav")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Run("WriteTo", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := NewWAVChunker(bytes.NewReader(data)).WriteTo(io.Discard); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Next", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var c Chunker = NewWAVChunker(bytes.NewReader(data))
+			for {
+				chunk, err := c.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					b.Fatal(err)
+				}
+				io.Discard.Write(chunk)
+			}
+		}
+	})
+}
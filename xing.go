@@ -0,0 +1,212 @@
+package main
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// Xing header flags
+const (
+	xingFrames  = 0x1
+	xingBytes   = 0x2
+	xingTOC     = 0x4
+	xingQuality = 0x8
+)
+
+// XingHeader is the VBR header stored in the first frame of many MP3 files.
+type XingHeader struct {
+	VBR    bool      // "Xing" tag; "Info" marks a CBR stream
+	Frames int       // number of frames, 0 if not present
+	Bytes  int64     // stream size in bytes, 0 if not present
+	HasTOC bool      // TOC is present and consistent with the stream
+	TOC    [100]byte // seek table, valid if HasTOC
+
+	// Encoder delay and padding in samples from the LAME tag following
+	// the Xing header, valid if HasLAME.
+	HasLAME        bool
+	EncoderDelay   int
+	EncoderPadding int
+}
+
+// xingOffset returns the offset of the Xing tag within a Layer III frame.
+func xingOffset(frame []byte) int {
+	off := 4 + sideInfoSize(frame)
+	if frame[1]&0x01 == 0 {
+		off += 2 // CRC
+	}
+	return off
+}
+
+// ParseXingHeader parses the Xing or Info header of the first frame of a stream.
+func ParseXingHeader(frame []byte) (XingHeader, bool) {
+	if len(frame) < 4 || !isLayerIII(frame) {
+		return XingHeader{}, false
+	}
+	off := xingOffset(frame)
+	if len(frame) < off+8 {
+		return XingHeader{}, false
+	}
+	p := frame[off:]
+	if !(compareID(p[0:4], "Xing") || compareID(p[0:4], "Info")) {
+		return XingHeader{}, false
+	}
+
+	h := XingHeader{VBR: compareID(p[0:4], "Xing")}
+	flags := binary.BigEndian.Uint32(p[4:8])
+	p = p[8:]
+
+	if flags&xingFrames != 0 {
+		if len(p) < 4 {
+			return XingHeader{}, false
+		}
+		h.Frames = int(binary.BigEndian.Uint32(p))
+		p = p[4:]
+	}
+	if flags&xingBytes != 0 {
+		if len(p) < 4 {
+			return XingHeader{}, false
+		}
+		h.Bytes = int64(binary.BigEndian.Uint32(p))
+		p = p[4:]
+	}
+	if flags&xingTOC != 0 {
+		if len(p) < len(h.TOC) {
+			return XingHeader{}, false
+		}
+		h.HasTOC = true
+		copy(h.TOC[:], p)
+		p = p[len(h.TOC):]
+	}
+	if flags&xingQuality != 0 {
+		if len(p) < 4 {
+			return h, true
+		}
+		p = p[4:]
+	}
+	h.parseLAME(p)
+	return h, true
+}
+
+// lameTagSize is the size of the LAME tag up to the delay and padding fields.
+const lameTagSize = 24
+
+// parseLAME reads the encoder delay and padding from the LAME tag in p.
+// The tag is also written by FFmpeg under the "Lavc" and "Lavf" names.
+func (h *XingHeader) parseLAME(p []byte) {
+	if len(p) < lameTagSize {
+		return
+	}
+	if !(compareID(p[0:4], "LAME") || compareID(p[0:4], "Lavc") || compareID(p[0:4], "Lavf")) {
+		return
+	}
+	h.HasLAME = true
+	h.EncoderDelay = int(p[21])<<4 | int(p[22])>>4
+	h.EncoderPadding = int(p[22]&0x0f)<<8 | int(p[23])
+}
+
+// Validate checks the header against the actual size of the stream and
+// marks the TOC unreliable if the header claims more bytes than the
+// stream holds, as seeking by the TOC would then land past the end.
+func (h *XingHeader) Validate(size int64) {
+	if h.Bytes > size {
+		h.HasTOC = false
+	}
+}
+
+// streamSize returns the total size of r if it is seekable.
+func streamSize(r io.Reader) (int64, bool) {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return 0, false
+	}
+	cur, err := s.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, false
+	}
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, false
+	}
+	if _, err := s.Seek(cur, io.SeekStart); err != nil {
+		return 0, false
+	}
+	return end, true
+}
+
+// XingHeader returns the Xing or Info header of the stream, if present.
+// It is available after the first call to Next. For seekable inputs the
+// header is validated against the stream size.
+func (c *MP3Chunker) XingHeader() (XingHeader, bool) {
+	if c.xing == nil {
+		return XingHeader{}, false
+	}
+	return *c.xing, true
+}
+
+// parseXing parses the Xing header of the first frame of the stream.
+func (c *MP3Chunker) parseXing(frame []byte) {
+	h, ok := ParseXingHeader(frame)
+	if !ok {
+		return
+	}
+	if size, ok := streamSize(c.src); ok {
+		h.Validate(size)
+	}
+	c.xing = &h
+
+	if c.trimSilence && h.HasLAME {
+		if params, err := frameParamsOf(frame); err == nil {
+			c.trimFrames = h.EncoderPadding / params.samplesPerFrame
+		}
+	}
+}
+
+// StreamInfo returns the frame count and stream size declared by the Xing
+// or Info header of the first frame, and whether the stream is VBR. The
+// counts are zero if there is no such header. Before the first call to
+// Next the first frame is read ahead and kept for Next to return.
+func (c *MP3Chunker) StreamInfo() (frames int, bytes int64, vbr bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.frames == 0 && !c.peekedFirst {
+		if err := c.peekFirstFrame(); err != nil {
+			return 0, 0, false, err
+		}
+	}
+	if c.xing == nil {
+		return 0, 0, false, nil
+	}
+	return c.xing.Frames, c.xing.Bytes, c.xing.VBR, nil
+}
+
+// peekFirstFrame peeks at the first frame to parse its Xing header and
+// hands its header back to the frame scanner.
+func (c *MP3Chunker) peekFirstFrame() error {
+	if c.err != nil {
+		return c.err
+	}
+	if err := c.start(); err != nil {
+		c.err = err
+		return err
+	}
+	hdr, err := c.findNextFrame()
+	if err != nil {
+		return err
+	}
+	frameLen, err := c.frameLength(hdr)
+	if err != nil {
+		return err
+	}
+	c.unread = hdr
+	body, err := c.r.Peek(frameLen - 4)
+	if len(body) < frameLen-4 {
+		if err == io.EOF && len(body) > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	frame := append(hdr[:4:4], body...)
+	c.peekedFirst = true
+	c.parseXing(frame)
+	return nil
+}
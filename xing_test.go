@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// makeXingStream returns an MPEG-1 Layer III stream whose first frame
+// carries a Xing header declaring the given stream size.
+func makeXingStream(t *testing.T, frames int, declared uint32) []byte {
+	t.Helper()
+	stream := makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, frames)
+
+	tag := stream[4+32:] // after the header and stereo side information
+	copy(tag, "Xing")
+	binary.BigEndian.PutUint32(tag[4:], xingFrames|xingBytes|xingTOC)
+	binary.BigEndian.PutUint32(tag[8:], uint32(frames))
+	binary.BigEndian.PutUint32(tag[12:], declared)
+	for i := 0; i < 100; i++ {
+		tag[16+i] = byte(i * 255 / 100)
+	}
+	return stream
+}
+
+func TestMP3ChunkerXingHeader(t *testing.T) {
+	const frames = 10
+
+	size := len(makeXingStream(t, frames, 0))
+	tests := []struct {
+		declared uint32
+		hasTOC   bool
+	}{
+		{uint32(size), true},
+		{uint32(size * 10), false},
+	}
+
+	for _, tt := range tests {
+		stream := makeXingStream(t, frames, tt.declared)
+		chunker := NewMP3Chunker(bytes.NewReader(stream), 8192, 0)
+		readAllChunks(t, chunker)
+
+		h, ok := chunker.XingHeader()
+		if !ok {
+			t.Fatalf("declared %d: XingHeader() not found", tt.declared)
+		}
+		if !h.VBR || h.Frames != frames || h.Bytes != int64(tt.declared) {
+			t.Errorf("declared %d: got %+v", tt.declared, h)
+		}
+		if h.HasTOC != tt.hasTOC {
+			t.Errorf("declared %d: HasTOC = %v, want %v", tt.declared, h.HasTOC, tt.hasTOC)
+		}
+	}
+}
+
+func TestParseXingHeaderShortFrame(t *testing.T) {
+	// Layer III frames cut before or within the Xing tag
+	for _, frame := range [][]byte{
+		{0xff, 0xfb, 0x90, 0x00},
+		append([]byte{0xff, 0xfb, 0x90, 0x00}, make([]byte, 32)...),
+		append(append([]byte{0xff, 0xfb, 0x90, 0x00}, make([]byte, 32)...), "Xing"...),
+	} {
+		if _, ok := ParseXingHeader(frame); ok {
+			t.Errorf("ParseXingHeader() of a %d-byte frame succeeded", len(frame))
+		}
+	}
+}
+
+func TestMP3ChunkerTrimTrailingSilence(t *testing.T) {
+	const frames, delay, padding = 20, 576, 2*1152 + 100
+
+	// CBR stream whose first frame carries an Info header with a LAME tag
+	hdr := []byte{0xff, 0xfb, 0x90, 0x00}
+	stream := makeFrames(t, hdr, frames)
+	size, _ := frameLength(hdr)
+	for i := 1; i < frames; i++ {
+		stream[i*size+40] = byte(i) // tell the frames apart
+	}
+	tag := stream[4+32:]
+	copy(tag, "Info")
+	binary.BigEndian.PutUint32(tag[4:], xingFrames)
+	binary.BigEndian.PutUint32(tag[8:], frames)
+	lame := tag[12:]
+	copy(lame, "LAME3.100")
+	lame[21], lame[22], lame[23] = delay>>4, delay&0x0f<<4|padding>>8, padding&0xff
+
+	chunker := NewMP3Chunker(bytes.NewReader(stream), 2048, 0, WithTrimTrailingSilence())
+	got := bytes.Join(readAllChunks(t, chunker), nil)
+
+	h, _ := chunker.XingHeader()
+	if !h.HasLAME || h.EncoderDelay != delay || h.EncoderPadding != padding {
+		t.Fatalf("got LAME delay %d, padding %d; want %d, %d", h.EncoderDelay, h.EncoderPadding, delay, padding)
+	}
+	if n := countFrames(t, got); n != frames-2 {
+		t.Errorf("got %d frames, want %d", n, frames-2)
+	}
+	if !bytes.Equal(got, stream[:(frames-2)*size]) {
+		t.Error("trimmed output does not end at the last audio frame")
+	}
+
+	// Without the option the padding frames are kept
+	got = bytes.Join(readAllChunks(t, NewMP3Chunker(bytes.NewReader(stream), 2048, 0)), nil)
+	if n := countFrames(t, got); n != frames {
+		t.Errorf("untrimmed: got %d frames, want %d", n, frames)
+	}
+}
+
+func TestMP3ChunkerStreamInfo(t *testing.T) {
+	const frames = 10
+	tagged := makeXingStream(t, frames, 4242)
+	plain := makeFrames(t, []byte{0xff, 0xfb, 0x90, 0x00}, frames)
+
+	tests := []struct {
+		input  []byte
+		frames int
+		bytes  int64
+		vbr    bool
+	}{
+		{tagged, frames, 4242, true},
+		{plain, 0, 0, false},
+	}
+
+	for i, tt := range tests {
+		chunker := NewMP3Chunker(bytes.NewReader(tt.input), 8192, 0)
+		n, size, vbr, err := chunker.StreamInfo()
+		if err != nil {
+			t.Fatalf("%d: StreamInfo() error: %v", i, err)
+		}
+		if n != tt.frames || size != tt.bytes || vbr != tt.vbr {
+			t.Errorf("%d: StreamInfo() = %d, %d, %v; want %d, %d, %v", i, n, size, vbr, tt.frames, tt.bytes, tt.vbr)
+		}
+		if got := bytes.Join(readAllChunks(t, chunker), nil); !bytes.Equal(got, tt.input) {
+			t.Errorf("%d: first frame not emitted after StreamInfo", i)
+		}
+	}
+}